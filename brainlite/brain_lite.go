@@ -1,6 +1,7 @@
 package brainlite
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -9,17 +10,23 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/zenmodel/zenmodel/core"
-	"github.com/zenmodel/zenmodel/internal/utils"
 	"github.com/zenmodel/zenmodel/internal/errors"
+	"github.com/zenmodel/zenmodel/internal/queue"
+	"github.com/zenmodel/zenmodel/internal/utils"
+	"github.com/zenmodel/zenmodel/processor"
 )
 
 const (
-	//  length of brain event queue
+	// initial capacity of brain event queue, the queue is unbounded
 	bQueueLen = 10
-	// default length of neuron process queue
+	// default initial capacity of neuron process queue, the queue is unbounded
 	defaultNQueueLen = 10
 	// default number of neuron process workers
 	defaultNWorkerNum = 4
+	// default interval that the priority of waiting neuron increases by 1
+	defaultNPriorityAging = time.Second
+	// name of default worker pool, neuron without pool label or with unknown pool is processed by it
+	defaultWorkerPool = ""
 )
 
 func BuildBrain(blueprint core.Blueprint, withOpts ...Option) *BrainLite {
@@ -64,11 +71,18 @@ func BuildBrain(blueprint core.Blueprint, withOpts ...Option) *BrainLite {
 	}).With().Caller().Timestamp().Logger().Level(zerolog.InfoLevel)
 	b.BrainMaintainer.nQueueLen = defaultNQueueLen
 	b.BrainMaintainer.nWorkerNum = defaultNWorkerNum
-	b.BrainMemory.datasourceName = fmt.Sprintf("%s.db", b.id)
+	b.BrainMaintainer.poolWorkerNum = make(map[string]int)
+	b.BrainMaintainer.priorityAging = defaultNPriorityAging
+	b.changelog.interval = defaultMemoryWatchInterval
+	b.changelog.kick = make(chan struct{}, 1)
+	b.changelog.keys = make(map[int64]any)
+	b.BrainMemory.reducers = blueprint.ListReducers()
 
 	for _, opt := range withOpts {
 		opt.apply(b)
 	}
+	// after options, so that it follows WithID, Python processors open the memory by brain ID
+	b.BrainMemory.datasourceName = fmt.Sprintf("%s.db", b.id)
 
 	b.logger = b.logger.With().Str("brainID", b.id).Logger()
 
@@ -89,8 +103,20 @@ type BrainLite struct {
 
 	// brain is in the Running state when there are 1 or more Activate neuron or 1 or more StandBy link.
 	state core.BrainState
+	// closing is true when brain is shutting down, brain no longer accepts triggers and activates neurons
+	closing bool
+	// runID is the ID of current run, renewed every time brain turns to Running
+	runID string
+	// runErr is the error of current run, e.g. *core.LimitError
+	runErr error
+	// runLimit is the first loop limit of current run which is routed to limit cast group
+	runLimit *core.LimitError
+	// runOutcome is the outcome of the end link which ends current run
+	runOutcome string
 	// brain memories
 	BrainMemory
+	// changelog polls changes of memory for watchers
+	changelog changelogWatch
 	BrainMaintainer
 
 	logger zerolog.Logger
@@ -99,16 +125,33 @@ type BrainLite struct {
 }
 
 type BrainMaintainer struct {
-	bQueue chan maintainEvent
+	bQueue *queue.Queue[maintainEvent]
 	stop   chan struct{}
+	// ctx is the root context of all neuron processes, cancel cancels it
+	ctx    context.Context
+	cancel context.CancelFunc
+	// statusMu is held by maintainer while handling event, so that status of neurons and links can be read safely
+	statusMu sync.RWMutex
+	// maxSteps is the max activations of all neurons in a run, 0 means unlimited
+	maxSteps int
+	// steps is the activations of run stepsRunID, changed by maintainer only
+	steps      int
+	stepsRunID string
 
 	NeuronRunner
 }
 
 type NeuronRunner struct {
-	nQueue     chan string
+	// neuron process queue of each worker pool, key is pool name
+	nQueues    map[string]*queue.PriorityQueue[activation]
 	nQueueLen  int
 	nWorkerNum int
+	// worker number of dedicated worker pools, key is pool name
+	poolWorkerNum map[string]int
+	// interval that the priority of waiting neuron increases by 1, avoid starvation of low priority neurons
+	priorityAging time.Duration
+	// inflight is the number of queued and running activations, guarded by BrainLite.mu
+	inflight int
 }
 
 func (b *BrainLite) TrigLinks(links ...core.Link) error {
@@ -146,28 +189,14 @@ func (b *BrainLite) SetMemory(keysAndValues ...interface{}) error {
 	if len(keysAndValues)%2 != 0 {
 		return fmt.Errorf("key and value are not paired")
 	}
-	if err := b.ensureMemoryInit(); err != nil {
-		return err
-	}
 
-	for i := 0; i < len(keysAndValues); i += 2 {
-		k := keysAndValues[i]
-		v := keysAndValues[i+1]
-		// TODO batch set
-		if err := b.BrainMemory.Set(k, v); err != nil {
-			return errors.Wrapf(err, "set memory failed")
-		}
-		b.logger.Debug().
-			Any("key", k).
-			Any("value", v).
-			Msg("set memory")
-	}
-
-	return nil
+	return b.updateMemory("", func(tx *memoryTx) error {
+		return tx.SetMemory(keysAndValues...)
+	})
 }
 
 func (b *BrainLite) GetMemory(key any) any {
-	if b.BrainMemory.db == nil {
+	if !b.BrainMemory.IsInit() {
 		return nil
 	}
 	v, err := b.BrainMemory.Get(key)
@@ -180,7 +209,7 @@ func (b *BrainLite) GetMemory(key any) any {
 }
 
 func (b *BrainLite) ExistMemory(key any) bool {
-	if b.BrainMemory.db == nil {
+	if !b.BrainMemory.IsInit() {
 		return false
 	}
 
@@ -193,24 +222,184 @@ func (b *BrainLite) ExistMemory(key any) bool {
 	return true
 }
 
+// DecodeMemory decodes memory into target by its stored JSON, it implements memory.Decoder
+func (b *BrainLite) DecodeMemory(key any, target any) (bool, error) {
+	if !b.BrainMemory.IsInit() {
+		return false, nil
+	}
+
+	return b.BrainMemory.Decode(key, target)
+}
+
 func (b *BrainLite) DeleteMemory(key any) {
-	if b.BrainMemory.db == nil {
+	b.deleteMemory("", key)
+}
+
+func (b *BrainLite) ClearMemory() {
+	b.clearMemory("")
+}
+
+func (b *BrainLite) deleteMemory(neuronID string, key any) {
+	if !b.BrainMemory.IsInit() {
 		return
 	}
 
-	if err := b.BrainMemory.Del(key); err != nil {
+	err := b.updateMemory(neuronID, func(tx *memoryTx) error {
+		tx.DeleteMemory(key)
+		return nil
+	})
+	if err != nil {
 		b.logger.Error().Err(err).Msg("delete memory failed")
 	}
 }
 
-func (b *BrainLite) ClearMemory() {
-	if b.BrainMemory.db == nil {
+func (b *BrainLite) clearMemory(neuronID string) {
+	if !b.BrainMemory.IsInit() {
 		return
 	}
 
-	if err := b.BrainMemory.Clear(); err != nil {
+	b.BrainMemory.txMu.Lock()
+	defer b.BrainMemory.txMu.Unlock()
+	if err := b.BrainMemory.Clear(neuronID); err != nil {
 		b.logger.Error().Err(err).Msg("clear memory failed")
 	}
+	b.kickChangelogWatch()
+}
+
+// ListMemoryKeys lists keys of all memories in no particular order, see processor.BrainContext.
+// Keys written by old versions without original key are listed as their hashed int64 keys
+func (b *BrainLite) ListMemoryKeys() ([]any, error) {
+	if !b.BrainMemory.IsInit() {
+		return nil, nil
+	}
+	keys, err := b.BrainMemory.Keys()
+	if err != nil {
+		return nil, errors.Wrapf(err, "list memory keys failed")
+	}
+
+	return keys, nil
+}
+
+// RangeMemory calls fn for each memory until fn returns false, see processor.BrainContext
+func (b *BrainLite) RangeMemory(fn func(key, value any) bool) error {
+	if !b.BrainMemory.IsInit() {
+		return nil
+	}
+	memories, err := b.BrainMemory.List()
+	if err != nil {
+		return errors.Wrapf(err, "range memory failed")
+	}
+	for _, m := range memories {
+		if !fn(m.key, m.value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// snapshotMemory returns all memories for ExportMemory, memories written without original key by old versions
+// are returned with their hashed keys
+func (b *BrainLite) snapshotMemory() ([]memoryWrite, error) {
+	if !b.BrainMemory.IsInit() {
+		return nil, nil
+	}
+
+	return b.BrainMemory.List()
+}
+
+// UpdateMemory runs fn in a memory transaction, see processor.BrainContext
+func (b *BrainLite) UpdateMemory(fn func(tx processor.MemoryTx) error) error {
+	return b.updateMemory("", func(tx *memoryTx) error {
+		return fn(tx)
+	})
+}
+
+// CompareAndSwapMemory sets memory of key to new if its current value equals old, see processor.BrainContext
+func (b *BrainLite) CompareAndSwapMemory(key, old, new any) (bool, error) {
+	var swapped bool
+	err := b.updateMemory("", func(tx *memoryTx) error {
+		var err error
+		swapped, err = tx.compareAndSwap(key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// updateMemory runs fn with write lock of memory held, tx reads memory and commits its writes in one SQLite
+// transaction if fn returns nil, so that writes of other processes are not lost. neuronID is the writer recorded
+// in changelog
+func (b *BrainLite) updateMemory(neuronID string, fn func(tx *memoryTx) error) error {
+	if err := b.ensureMemoryInit(); err != nil {
+		return err
+	}
+	b.BrainMemory.txMu.Lock()
+	defer b.BrainMemory.txMu.Unlock()
+
+	var tx *memoryTx
+	var txErr error
+	watching := b.BrainMemory.watchers.len() > 0
+	err := b.BrainMemory.Update(neuronID, func(lookup memoryLookup) ([]memoryWrite, error) {
+		var lookupErr error
+		tx = newMemoryTx(func(key any) (any, bool) {
+			v, ok, err := lookup(key)
+			if err != nil && lookupErr == nil {
+				lookupErr = errors.Wrapf(err, "get memory failed")
+			}
+			return v, ok
+		}, b.BrainMemory.reducers)
+		if txErr = tx.run(fn); txErr != nil {
+			return nil, txErr
+		}
+		if txErr = lookupErr; txErr != nil {
+			return nil, txErr
+		}
+		if watching {
+			for _, w := range tx.writes {
+				b.learnMemoryKeys(w.key)
+			}
+		}
+		return tx.writes, nil
+	})
+	if txErr != nil {
+		return txErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "set memory failed")
+	}
+	if len(tx.writes) == 0 {
+		return nil
+	}
+	if watching {
+		b.kickChangelogWatch()
+	}
+	for _, w := range tx.writes {
+		if w.del {
+			b.logger.Debug().Any("key", w.key).Msg("delete memory")
+			continue
+		}
+		b.logger.Debug().
+			Any("key", w.key).
+			Any("value", w.value).
+			Msg("set memory")
+	}
+
+	return nil
+}
+
+// lookupMemory reads memory, committed SQLite transaction is visible all together
+func (b *BrainLite) lookupMemory(key any) (any, bool) {
+	if !b.BrainMemory.IsInit() {
+		return nil, false
+	}
+	v, ok, err := b.BrainMemory.Lookup(key)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("get memory failed")
+		return nil, false
+	}
+
+	return v, ok
 }
 
 func (b *BrainLite) GetState() core.BrainState {
@@ -226,14 +415,60 @@ func (b *BrainLite) Wait() {
 	b.mu.Unlock()
 }
 
-func (b *BrainLite) Shutdown() {
-	b.logger.Info().Msg("brain local shutdown")
-	close(b.BrainMaintainer.nQueue)
-	close(b.BrainMaintainer.bQueue)
-	if err := b.BrainMemory.Close(); err != nil {
-		b.logger.Error().Err(err).Msg("close memory failed")
+func (b *BrainLite) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.state == core.BrainStateShutdown { // maintainer not started, release memory only
+		b.mu.Unlock()
+		return b.closeMemory()
+	}
+	if b.closing { // shutting down by other goroutine
+		b.mu.Unlock()
+		return b.waitUntil(ctx, func() bool {
+			return b.state == core.BrainStateShutdown
+		})
 	}
-	b.setState(core.BrainStateShutdown)
+	b.closing = true
+	b.mu.Unlock()
+	b.logger.Info().Msg("brain lite shutdown")
+
+	// drain active neurons, cancel them if ctx done
+	err := b.waitUntil(ctx, func() bool {
+		return b.inflight == 0
+	})
+	b.mu.Lock()
+	b.closeQueues()
+	b.mu.Unlock()
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("brain shutdown before active neurons done, cancel them")
+		// processors and memory are still used by canceled neurons until they return
+		_ = b.waitUntil(context.Background(), func() bool {
+			return b.inflight == 0
+		})
+	}
+
+	for _, neu := range b.neurons {
+		closer, ok := neu.spec.processor.(processor.Closer)
+		if !ok {
+			continue
+		}
+		if closeErr := closer.Close(); closeErr != nil {
+			b.logger.Error().Err(closeErr).Str("neuronID", neu.id).Msg("close processor failed")
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if closeErr := b.closeMemory(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	b.mu.Lock()
+	b.closing = false
+	b.state = core.BrainStateShutdown
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	return err
 }
 
 func (b *BrainLite) trigLinks(linkIDs ...string) error {
@@ -241,54 +476,75 @@ func (b *BrainLite) trigLinks(linkIDs ...string) error {
 		return nil
 	}
 
+	// ensure brain maintainer start
+	if err := b.ensureMaintainerStart(); err != nil {
+		return err
+	}
+
 	if err := b.ensureMemoryInit(); err != nil {
 		// TODO wrap error
 		return err
 	}
 
-	// ensure brain maintainer start
-	b.ensureMaintainerStart()
-
-	// goroutine wait
-	var wg sync.WaitGroup
+	events := make([]maintainEvent, 0, len(linkIDs))
 	for _, linkID := range linkIDs {
-		l, ok := b.links[linkID]
-		if !ok {
+		if _, ok := b.links[linkID]; !ok {
 			continue
 		}
-
-		wg.Add(1)
-		go b.trigLink(&wg, l)
+		events = append(events, maintainEvent{
+			kind:   eventKindLink,
+			action: eventActionLinkTrig,
+			id:     linkID,
+		})
 	}
-	wg.Wait()
-
-	b.refreshState()
+	// link state is changed by maintainer, wait them handled so that brain is running after trig
+	b.publishEventAndWait(events...)
 
 	return nil
 }
 
-func (b *BrainLite) trigLink(wg *sync.WaitGroup, l *link) {
-	defer wg.Done()
+func (b *BrainLite) ensureMemoryInit() error {
+	// Init is idempotent
+	return b.BrainMemory.Init()
+}
+
+func (b *BrainLite) closeMemory() error {
+	b.changelog.mu.Lock()
+	b.stopChangelogWatch()
+	b.changelog.mu.Unlock()
+	b.BrainMemory.watchers.closeAll()
+	if err := b.BrainMemory.Close(); err != nil {
+		b.logger.Error().Err(err).Msg("close memory failed")
+		return err
+	}
 
-	if l.status.state != core.LinkStateReady {
-		// change link state as ready
-		l.status.state = core.LinkStateReady
+	return nil
+}
 
-		// send maintain event
-		b.publishEvent(maintainEvent{
-			kind:   eventKindLink,
-			action: eventActionLinkReady,
-			id:     l.id,
-		})
+// memoryKeys returns the number of memories
+func (b *BrainLite) memoryKeys() int {
+	if !b.BrainMemory.IsInit() {
+		return 0
+	}
+	n, err := b.BrainMemory.Len()
+	if err != nil {
+		b.logger.Error().Err(err).Msg("count memory failed")
+		return 0
 	}
 
-	return
+	return n
 }
 
-func (b *BrainLite) ensureMemoryInit() error {
-	if b.BrainMemory.db != nil {
-		return nil
+// memoryBytes returns the total size of JSON encoded memories
+func (b *BrainLite) memoryBytes() int64 {
+	if !b.BrainMemory.IsInit() {
+		return 0
+	}
+	n, err := b.BrainMemory.Size()
+	if err != nil {
+		b.logger.Error().Err(err).Msg("size memory failed")
+		return 0
 	}
 
-	return b.BrainMemory.Init()
-}
\ No newline at end of file
+	return n
+}
@@ -9,6 +9,16 @@ type maintainEvent struct {
 	kind   eventKind
 	action eventAction
 	id     string
+	// seq is the activation sequence of neuron, used by neuron processed event to drop stale result
+	seq int
+	// err is the process error of neuron, used by neuron processed event
+	err error
+	// payload is the output of neuron, used by neuron processed and cast anyway event
+	payload interface{}
+	// cast is the cast decision of neuron, used by neuron processed, try cast and cast anyway event
+	cast castDecision
+	// done will be closed after the event is handled by maintainer if it is not nil
+	done chan struct{}
 }
 
 type eventKind string
@@ -22,25 +32,65 @@ const (
 	eventActionLinkInit          eventAction = "link_init"
 	eventActionLinkReady         eventAction = "link_ready"
 	eventActionLinkWait          eventAction = "link_wait"
+	eventActionLinkTrig          eventAction = "link_trig"
 	eventActionNeuronTryActivate eventAction = "try_activate_neuron"
 	eventActionNeuronTryInactive eventAction = "try_inactive_neuron"
+	eventActionNeuronProcessed   eventAction = "neuron_processed"
 	eventActionNeuronTryCast     eventAction = "try_cast"
 	eventActionNeuronCastAnyway  eventAction = "cast_anyway"
-	eventActionBrainSleep        eventAction = "brain_sleep"
-	eventActionBrainShutdown     eventAction = "brain_shutdown"
+	eventActionNeuronCastRetry   eventAction = "cast_retry"
+	// trigger group timeout, seq of event is the ID of timeout
+	eventActionNeuronTriggerTimeout eventAction = "trigger_timeout"
+	eventActionBrainSleep           eventAction = "brain_sleep"
+	eventActionBrainShutdown        eventAction = "brain_shutdown"
 )
 
 func (m maintainEvent) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("kind", string(m.kind)).
 		Str("action", string(m.action)).
 		Str("id", m.id)
+	if m.kind == eventKindNeuron {
+		e.Int("seq", m.seq)
+	}
+	if m.err != nil {
+		e.AnErr("err", m.err)
+	}
 }
 
-func (b *BrainLite) publishEvent(event maintainEvent) {
-	if b.getState() == core.BrainStateShutdown || b.bQueue == nil { // 关闭中或没启动
-		return
+// publishEvent publishes event to maintainer, it returns false if the maintainer is not running.
+func (b *BrainLite) publishEvent(event maintainEvent) bool {
+	b.mu.Lock()
+	state, bQueue := b.state, b.bQueue
+	b.mu.Unlock()
+	if state == core.BrainStateShutdown || bQueue == nil { // 关闭中或没启动
+		return false
 	}
 	b.logger.Debug().Interface("event", event).Msg("publish maintain event")
 
-	b.bQueue <- event
+	// bQueue is unbounded, so publish never blocks even if it is called by the maintainer itself
+	return bQueue.Push(event)
+}
+
+// publishEventAndWait publishes events and blocks until the maintainer handled them or the maintainer stopped.
+// It must not be called by the maintainer itself.
+func (b *BrainLite) publishEventAndWait(events ...maintainEvent) {
+	b.mu.Lock()
+	stop := b.stop
+	b.mu.Unlock()
+
+	dones := make([]chan struct{}, 0, len(events))
+	for _, event := range events {
+		event.done = make(chan struct{})
+		if b.publishEvent(event) {
+			dones = append(dones, event.done)
+		}
+	}
+
+	for _, done := range dones {
+		select {
+		case <-done:
+		case <-stop:
+			return
+		}
+	}
 }
@@ -15,11 +15,24 @@ type linkSpec struct {
 	from string
 	// to neuron ID
 	to string
+	// outcome is the outcome name of end link
+	outcome string
+	// condition is the predicate of conditional link, nil if link is not conditional
+	condition core.LinkCondition
 }
 
 type linkStatus struct {
 	state core.LinkState
-	count struct {
+	// payload is carried by the Ready link, it is the output of source neuron when it casts
+	payload interface{}
+	// pendingCasts is the payloads of ContinueCast which are not cast yet, because the link is not consumed
+	pendingCasts []interface{}
+	// lateSeq is the activation seq of source neuron which is processing when the trigger group of link fired
+	// without it, the cast of that activation is ignored. It is 0 if link is not late
+	lateSeq int
+	// limited is true if the payload is cast to limit cast group of source neuron, instead of its output
+	limited bool
+	count   struct {
 		// from 执行完整，开始尝试传递的次数
 		process int
 		// 传递成功的次数
@@ -33,8 +46,10 @@ func newLink(l core.Link) *link {
 	return &link{
 		id: l.GetID(),
 		spec: linkSpec{
-			from: l.GetSrcNeuronID(),
-			to:   l.GetDestNeuronID(),
+			from:      l.GetSrcNeuronID(),
+			to:        l.GetDestNeuronID(),
+			outcome:   l.GetLabels()[core.LinkLabelOutcome],
+			condition: l.GetCondition(),
 		},
 		status: linkStatus{
 			state: core.LinkStateInit,
@@ -49,3 +64,20 @@ func (l *link) isEntryLink() bool {
 
 	return false
 }
+
+// popPendingCast pops the payload of the earliest pending ContinueCast
+func (l *link) popPendingCast() interface{} {
+	payload := l.status.pendingCasts[0]
+	l.status.pendingCasts = l.status.pendingCasts[1:]
+
+	return payload
+}
+
+// linkInput is the payload of in-link which triggered an activation
+type linkInput struct {
+	linkID  string
+	from    string
+	payload interface{}
+	// limited is true if the link is cast to limit cast group, see linkStatus.limited
+	limited bool
+}
@@ -1,52 +1,104 @@
 package brainlite
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/zenmodel/zenmodel/core"
 	"github.com/zenmodel/zenmodel/internal/errors"
+	"github.com/zenmodel/zenmodel/internal/queue"
+	"github.com/zenmodel/zenmodel/internal/utils"
 	"github.com/zenmodel/zenmodel/processor"
 )
 
-func (b *BrainLite) ensureMaintainerStart() {
-	if b.getState() == core.BrainStateShutdown {
+func (b *BrainLite) ensureMaintainerStart() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closing {
+		return core.ErrBrainShuttingDown
+	}
+	if b.state == core.BrainStateShutdown {
 		b.maintainerStart()
-		b.setState(core.BrainStateSleeping)
+		b.state = core.BrainStateSleeping
+		b.cond.Broadcast()
 	}
 
-	return
+	return nil
 }
 
+// maintainerStart should be called with b.mu locked
 func (b *BrainLite) maintainerStart() {
 	b.logger.Info().
 		Int("neuronWorkerNum", b.nWorkerNum).
 		Int("neuronQueueLen", b.nQueueLen).
+		Interface("workerPools", b.poolWorkerNum).
+		Dur("priorityAging", b.priorityAging).
 		Msg("brain maintainer start")
 	// 关闭残留的队列，相关的 goroutine 也会随之终结
-	if b.getState() != core.BrainStateShutdown {
-		b.Shutdown()
-	}
+	b.closeQueues()
 
 	// new
-	b.nQueue = make(chan string, b.nQueueLen)
-	b.bQueue = make(chan maintainEvent, bQueueLen)
-
-	for i := 0; i < b.nWorkerNum; i++ {
-		go b.runNeuronWorker()
+	b.bQueue = queue.New[maintainEvent](bQueueLen)
+	b.stop = make(chan struct{})
+	b.inflight = 0
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+
+	b.nQueues = make(map[string]*queue.PriorityQueue[activation], len(b.poolWorkerNum)+1)
+	workerNum := map[string]int{defaultWorkerPool: b.nWorkerNum}
+	for pool, num := range b.poolWorkerNum {
+		workerNum[pool] = num
+	}
+	for pool, num := range workerNum {
+		nQueue := queue.NewPriority[activation](b.nQueueLen, b.priorityAging)
+		b.nQueues[pool] = nQueue
+		for i := 0; i < num; i++ {
+			go b.runNeuronWorker(nQueue)
+		}
 	}
-	go b.runBrainMaintainer()
+	go b.runBrainMaintainer(b.bQueue)
 
 }
 
-func (b *BrainLite) runBrainMaintainer() {
-	for msg := range b.bQueue {
+// closeQueues stops maintainer and workers, it cancels running neurons and discards queued activations.
+// It should be called with b.mu locked
+func (b *BrainLite) closeQueues() {
+	for _, nQueue := range b.nQueues {
+		// discarded activations will never be done
+		b.inflight -= nQueue.Close()
+	}
+	if b.bQueue != nil {
+		b.bQueue.Close()
+	}
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+}
+
+// runBrainMaintainer is the only goroutine which changes the state of neurons and links
+func (b *BrainLite) runBrainMaintainer(bQueue *queue.Queue[maintainEvent]) {
+	for {
+		msg, ok := bQueue.Pop()
+		if !ok {
+			return
+		}
 		b.maintain(msg)
 	}
 }
 
 func (b *BrainLite) maintain(event maintainEvent) {
 	b.logger.Debug().Interface("event", event).Msg("got a maintain event")
+	b.statusMu.Lock()
+	defer b.statusMu.Unlock()
+	if event.done != nil {
+		defer close(event.done)
+	}
 
 	switch event.kind {
 	case eventKindLink:
@@ -55,7 +107,7 @@ func (b *BrainLite) maintain(event maintainEvent) {
 			return
 		}
 	case eventKindNeuron:
-		if err := b.handleNeuronEvent(event.action, event.id); err != nil {
+		if err := b.handleNeuronEvent(event); err != nil {
 			b.logger.Error().Err(err).Msg("handle neuron event error")
 			return
 		}
@@ -88,6 +140,14 @@ func (b *BrainLite) handleLinkEvent(action eventAction, linkID string) error {
 		// do nothing
 	case eventActionLinkWait:
 		// do nothing
+	case eventActionLinkTrig:
+		if l.status.state == core.LinkStateReady {
+			return nil
+		}
+		// triggered by external signal, brain should be running
+		l.status.state = core.LinkStateReady
+		b.setState(core.BrainStateRunning)
+		fallthrough
 	case eventActionLinkReady:
 		dest, ok := b.neurons[l.spec.to]
 		if !ok {
@@ -109,24 +169,39 @@ func (b *BrainLite) handleLinkEvent(action eventAction, linkID string) error {
 	return nil
 }
 
-func (b *BrainLite) handleNeuronEvent(action eventAction, neuronID string) error {
-	n, ok := b.neurons[neuronID]
+func (b *BrainLite) handleNeuronEvent(event maintainEvent) error {
+	n, ok := b.neurons[event.id]
 	if !ok {
-		return errors.ErrNeuronNotFound(neuronID)
+		return errors.ErrNeuronNotFound(event.id)
 	}
 
-	switch action {
+	switch event.action {
 	case eventActionNeuronTryInactive:
-		// do nothing for now, wait current neuron done and inactive
-		// TODO 主动 cancel neuron process
+		// cancel current process, neuron will be inactive after processed
+		if n.status.state == core.NeuronStateActivated && n.status.cancel != nil {
+			n.status.cancel()
+		}
 	case eventActionNeuronTryActivate:
 		return b.tryActivateNeuron(n)
+	case eventActionNeuronProcessed:
+		return b.neuronProcessed(n, event.seq, event.err, event.payload, event.cast)
 	case eventActionNeuronTryCast:
-		return b.neuronCast(n, false)
+		return b.neuronCast(n, false, event.cast)
 	case eventActionNeuronCastAnyway:
-		return b.neuronCast(n, true)
+		if n.status.state != core.NeuronStateActivated || n.status.seq != event.seq {
+			b.logger.Debug().
+				Str("neuronID", n.id).
+				Msg("neuron is not active, ignore continue cast")
+			return nil
+		}
+		n.status.output = event.payload
+		return b.neuronCast(n, true, event.cast)
+	case eventActionNeuronCastRetry:
+		return b.neuronCastRetry(n)
+	case eventActionNeuronTriggerTimeout:
+		return b.triggerGroupTimeout(n, event.seq)
 	default:
-		return fmt.Errorf("unsupported neuron action: %s", action)
+		return fmt.Errorf("unsupported neuron action: %s", event.action)
 	}
 
 	return nil
@@ -135,10 +210,13 @@ func (b *BrainLite) handleNeuronEvent(action eventAction, neuronID string) error
 func (b *BrainLite) handleBrainEvent(action eventAction) error {
 	switch action {
 	case eventActionBrainSleep:
-		b.ForceSleep()
+		b.forceSleep()
 		return nil
 	case eventActionBrainShutdown:
-		b.Shutdown()
+		// shutdown waits active neurons, should not block maintainer
+		go func() {
+			_ = b.Shutdown(context.Background())
+		}()
 		return nil
 	default:
 		return fmt.Errorf("unsupported brain action: %s", action)
@@ -146,33 +224,187 @@ func (b *BrainLite) handleBrainEvent(action eventAction) error {
 }
 
 func (b *BrainLite) tryActivateNeuron(n *neuron) error {
-	if n.status.state == core.NeuronStateActivated {
-		b.logger.Debug().Str("neuronID", n.id).Msg("neuron already activated")
+	if b.isClosing() {
+		b.logger.Debug().Str("neuronID", n.id).Msg("brain is shutting down, neuron should not be activated")
+		return nil
+	}
+	if n.status.state == core.NeuronStateActivated && !b.retriggerNeuron(n) {
+		b.startTriggerTimeouts(n)
 		return nil
 	}
 
 	should := b.ifNeuronShouldActivate(n)
 	if !should {
 		b.logger.Debug().Str("neuronID", n.id).Msg("neuron should not be activated")
+		b.startTriggerTimeouts(n)
 		return nil
 	}
+	group, trigLinks, _ := satisfiedTriggerGroup(n)
+	inputs := b.consumeTriggerGroup(n, group, trigLinks)
 
-	// should END, send brain sleep message
+	// should END, brain sleep
 	if n.id == core.EndNeuronID {
-		b.logger.Info().Msg("arrival at END neuron")
-		b.publishEvent(maintainEvent{
-			kind:   eventKindBrain,
-			action: eventActionBrainSleep,
-		})
+		outcome := endOutcome(trigLinks)
+		b.logger.Info().Str("outcome", outcome).Msg("arrival at END neuron")
+		b.mu.Lock()
+		b.runOutcome = outcome
+		b.mu.Unlock()
+		b.forceSleep()
 		return nil
 	}
 
-	b.publishEventActivateNeuron(n.id)
+	runID := b.getRunID()
+	if err := b.checkLoopLimits(n, runID, limitRouted(inputs)); err != nil {
+		b.loopLimitExceeded(n, err)
+		return nil
+	}
+
+	n.status.state = core.NeuronStateActivated
+	n.status.seq++
+	n.status.runID = runID
+	n.status.inputs = inputs
+	n.status.output = nil
+	n.status.count.process++
+	// out-link set wait
+	for _, links := range n.spec.castGroups {
+		for _, l := range links {
+			l.status.state = core.LinkStateWait
+		}
+	}
+
+	b.publishEventActivateNeuron(n)
+
+	return nil
+}
+
+// checkLoopLimits counts the activation of neuron in run, it returns *core.LimitError if a loop limit is hit.
+// Neuron routed by limit cast group can be activated beyond max steps, so that the run can be wrapped up
+func (b *BrainLite) checkLoopLimits(n *neuron, runID string, routed bool) error {
+	if b.stepsRunID != runID {
+		b.steps = 0
+		b.stepsRunID = runID
+	}
+	if n.status.runID != runID {
+		n.status.runActivations = 0
+	}
+
+	if b.maxSteps > 0 && b.steps >= b.maxSteps && !routed {
+		return &core.LimitError{NeuronID: n.id, Limit: b.maxSteps, Err: core.ErrMaxStepsExceeded}
+	}
+	if n.spec.maxActivations > 0 && n.status.runActivations >= n.spec.maxActivations {
+		return &core.LimitError{NeuronID: n.id, Limit: n.spec.maxActivations, Err: core.ErrMaxActivationsExceeded}
+	}
+	b.steps++
+	n.status.runActivations++
 
 	return nil
 }
 
-func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool) error {
+// loopLimitExceeded casts to the limit cast group of neuron instead of activation, or ends the run with error.
+// The neuron is not processed, so it casts nil payload
+func (b *BrainLite) loopLimitExceeded(n *neuron, err error) {
+	if links, ok := n.spec.castGroups[n.spec.limitCastGroup]; ok && n.spec.limitCastGroup != "" {
+		b.logger.Warn().Err(err).Str("neuronID", n.id).Str("castGroup", n.spec.limitCastGroup).
+			Msg("loop limit exceeded, cast to limit cast group")
+		if limitErr, ok := err.(*core.LimitError); ok {
+			b.mu.Lock()
+			if b.runLimit == nil {
+				b.runLimit = limitErr
+			}
+			b.mu.Unlock()
+		}
+		for _, l := range links {
+			b.castLink(l, nil)
+			if l.status.state == core.LinkStateReady {
+				l.status.limited = true
+			}
+		}
+		return
+	}
+
+	b.logger.Error().Err(err).Str("neuronID", n.id).Msg("loop limit exceeded, end the run")
+	b.mu.Lock()
+	if b.runErr == nil {
+		b.runErr = err
+	}
+	b.mu.Unlock()
+	b.forceSleep()
+}
+
+func (b *BrainLite) neuronProcessed(n *neuron, seq int, processErr error, output interface{}, cast castDecision) error {
+	// brain has been forced to sleep or neuron has been activated again, the result is stale
+	if n.status.state != core.NeuronStateActivated || n.status.seq != seq {
+		b.logger.Debug().
+			Str("neuronID", n.id).
+			Int("seq", seq).
+			Msg("drop stale neuron process result")
+		return nil
+	}
+
+	n.status.state = core.NeuronStateInactive
+	n.status.output = output
+	if n.status.cancel != nil {
+		n.status.cancel()
+		n.status.cancel = nil
+	}
+	// in-links are consumed, cast the pending ContinueCast of upstream neurons
+	defer b.flushPendingCasts(n)
+	// out-links not cast by this activation are not late any more
+	defer b.clearLateLinks(n, seq)
+	// re-check trigger groups, in-links which became Ready while processing activate neuron again
+	defer b.publishEvent(maintainEvent{
+		kind:   eventKindNeuron,
+		action: eventActionNeuronTryActivate,
+		id:     n.id,
+	})
+	if processErr != nil {
+		n.status.count.failed++
+		return nil
+	}
+	n.status.count.succeed++
+
+	return b.neuronCast(n, false, cast)
+}
+
+// clearLateLinks clears late out-links of neuron activation which has been processed
+func (b *BrainLite) clearLateLinks(n *neuron, seq int) {
+	for _, links := range n.spec.castGroups {
+		for _, l := range links {
+			if l.status.lateSeq == seq {
+				l.status.lateSeq = 0
+			}
+		}
+	}
+}
+
+// castDecision is the cast group selected by selector of neuron and the conditions of its conditional links.
+// It is decided by the goroutine of process with its BrainContext, so that user functions do not run in maintainer
+type castDecision struct {
+	group string
+	// conditions is the result of conditional links in group, key is link ID
+	conditions map[string]bool
+}
+
+// decideCast runs selector and conditions of neuron with reader, it should not be called by maintainer
+func decideCast(n *neuron, reader processor.BrainContextReader) castDecision {
+	cast := castDecision{group: processor.DefaultCastGroupName}
+	if n.spec.selector != nil {
+		cast.group = n.spec.selector.Select(reader)
+	}
+	for _, l := range n.spec.castGroups[cast.group] {
+		if l.spec.condition == nil {
+			continue
+		}
+		if cast.conditions == nil {
+			cast.conditions = make(map[string]bool)
+		}
+		cast.conditions[l.id] = l.spec.condition(reader)
+	}
+
+	return cast
+}
+
+func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool, cast castDecision) error {
 	if !isCastAnyway && n.status.state != core.NeuronStateInactive {
 		b.logger.Debug().
 			Str("neuronID", n.id).
@@ -184,31 +416,28 @@ func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool) error {
 		Str("neuronID", n.id).
 		Msg("neuron try to cast")
 
-	// 决策出边/传导组
-	var selectedGroup string
-	if n.spec.selector != nil {
-		selectedGroup = n.spec.selector.Select(&brainContext{
-			b:               b,
-			currentNeuronID: n.id,
-		})
-	} else {
-		selectedGroup = processor.DefaultCastGroupName
-	}
+	// 出边/传导组已经由处理 Neuron 的 goroutine 决策
+	selectedGroup := cast.group
 
 	// 选中的 cast group 中的 link 状态为 wait 的设置为 ready，SendMessage （为 init 的则不改变）
 	selectedLinks := make(map[string]struct{})
+	var pending bool
 
 	for _, l := range n.spec.castGroups[selectedGroup] {
+		// 条件 link 的条件不满足时视为未选中
+		if l.spec.condition != nil && !cast.conditions[l.id] {
+			continue
+		}
 		selectedLinks[l.id] = struct{}{}
 
 		switch l.status.state {
 		case core.LinkStateWait:
-			l.status.state = core.LinkStateReady
-			b.publishEvent(maintainEvent{
-				kind:   eventKindLink,
-				action: eventActionLinkReady,
-				id:     l.id,
-			})
+			if isCastAnyway && b.isLinkBusy(l) {
+				l.status.pendingCasts = append(l.status.pendingCasts, n.status.output)
+				pending = true
+			} else {
+				b.castLink(l, n.status.output)
+			}
 
 		case core.LinkStateInit:
 			if !isCastAnyway {
@@ -216,13 +445,11 @@ func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool) error {
 					Str("neuronID", n.id).
 					Str("link", l.id).
 					Msg("link on init state, will not cast")
+			} else if b.isLinkBusy(l) {
+				l.status.pendingCasts = append(l.status.pendingCasts, n.status.output)
+				pending = true
 			} else {
-				l.status.state = core.LinkStateReady
-				b.publishEvent(maintainEvent{
-					kind:   eventKindLink,
-					action: eventActionLinkReady,
-					id:     l.id,
-				})
+				b.castLink(l, n.status.output)
 			}
 
 		case core.LinkStateReady:
@@ -232,19 +459,22 @@ func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool) error {
 					Str("link", l.id).
 					Msg("link already cast, will not cast again")
 			} else {
-				// TODO 通过 neuron label 设置指数增长间隔以及最大重试次数配置
-				go func() {
-					time.Sleep(500 * time.Millisecond)
-					b.publishEvent(maintainEvent{
-						kind:   eventKindNeuron,
-						action: eventActionNeuronCastAnyway,
-						id:     n.id,
-					})
-				}()
+				// 下游还没有消费上一次的传播, 暂存起来等待重试或者下游消费之后再传播
+				l.status.pendingCasts = append(l.status.pendingCasts, n.status.output)
+				pending = true
 			}
 		}
 
 	}
+	if len(selectedLinks) == 0 {
+		b.logger.Debug().
+			Str("neuronID", n.id).
+			Str("castGroup", selectedGroup).
+			Msg("no out-link selected, neuron casts nothing")
+	}
+	if pending {
+		b.scheduleCastRetry(n)
+	}
 
 	for _, links := range n.spec.castGroups {
 		for _, l := range links {
@@ -266,23 +496,181 @@ func (b *BrainLite) neuronCast(n *neuron, isCastAnyway bool) error {
 	return nil
 }
 
+// isLinkBusy reports whether the link can not take a ContinueCast now, it is used by ContinueCast.
+// The link is busy when it is not consumed yet, or the destination neuron is active which would lose the cast.
+func (b *BrainLite) isLinkBusy(l *link) bool {
+	if l.status.state == core.LinkStateReady {
+		return true
+	}
+	dest, ok := b.neurons[l.spec.to]
+
+	return ok && dest.status.state == core.NeuronStateActivated
+}
+
+func (b *BrainLite) castLink(l *link, payload interface{}) {
+	if src, ok := b.neurons[l.spec.from]; ok && l.status.lateSeq != 0 && l.status.lateSeq == src.status.seq {
+		// trigger group has fired without this link, ignore the late cast
+		b.logger.Debug().Str("link", l.id).Msg("ignore late link")
+		l.status.state = core.LinkStateInit
+		l.status.lateSeq = 0
+		return
+	}
+	l.status.state = core.LinkStateReady
+	l.status.payload = payload
+	l.status.limited = false
+	b.publishEvent(maintainEvent{
+		kind:   eventKindLink,
+		action: eventActionLinkReady,
+		id:     l.id,
+	})
+}
+
+// scheduleCastRetry retries pending ContinueCast of neuron by its CastRetryPolicy.
+// In stream mode, pending casts only wait for the destination neuron finished.
+func (b *BrainLite) scheduleCastRetry(n *neuron) {
+	if n.spec.castMode == core.CastModeStream || n.status.castRetry.scheduled {
+		return
+	}
+
+	policy := n.spec.castRetry
+	n.status.castRetry.attempts++
+	if policy.MaxAttempts > 0 && n.status.castRetry.attempts > policy.MaxAttempts {
+		n.status.castRetry.attempts = 0
+		if policy.Overflow == core.CastOverflowQueue {
+			b.logger.Debug().
+				Str("neuronID", n.id).
+				Msg("cast retry attempts exhausted, queue pending casts until consumed")
+			return
+		}
+		b.logger.Warn().
+			Str("neuronID", n.id).
+			Msg("cast retry attempts exhausted, drop pending casts")
+		for _, l := range n.outLinks() {
+			l.status.pendingCasts = nil
+		}
+		return
+	}
+
+	n.status.castRetry.scheduled = true
+	delay := policy.Backoff(n.status.castRetry.attempts)
+	time.AfterFunc(delay, func() {
+		b.publishEvent(maintainEvent{
+			kind:   eventKindNeuron,
+			action: eventActionNeuronCastRetry,
+			id:     n.id,
+		})
+	})
+}
+
+func (b *BrainLite) neuronCastRetry(n *neuron) error {
+	n.status.castRetry.scheduled = false
+
+	var pending bool
+	for _, l := range n.outLinks() {
+		if len(l.status.pendingCasts) == 0 {
+			continue
+		}
+		if b.isLinkBusy(l) {
+			pending = true
+			continue
+		}
+		b.castLink(l, l.popPendingCast())
+		pending = pending || len(l.status.pendingCasts) > 0
+	}
+
+	if pending {
+		b.scheduleCastRetry(n)
+	} else {
+		n.status.castRetry.attempts = 0
+	}
+
+	return nil
+}
+
+// flushPendingCasts casts one pending ContinueCast of each in-link, after the neuron consumed them
+func (b *BrainLite) flushPendingCasts(n *neuron) {
+	for _, l := range n.inLinks() {
+		if len(l.status.pendingCasts) == 0 || l.status.state == core.LinkStateReady {
+			continue
+		}
+		b.castLink(l, l.popPendingCast())
+	}
+}
+
 func (b *BrainLite) ifNeuronShouldActivate(neu *neuron) bool {
 	state := b.getState()
 	if state == core.BrainStateSleeping || state == core.BrainStateShutdown {
 		return false
 	}
 
-	// 如果任一触发组中的 link 全都是 Ready, 则应该 activate neuron
-	for _, links := range neu.spec.triggerGroups {
-		trigLinks := make([]*link, 0)
+	_, _, ok := satisfiedTriggerGroup(neu)
+
+	return ok
+}
+
+// satisfiedTriggerGroup returns the trigger group which should fire and its Ready links.
+// A trigger group fires when all links are Ready, or Quorum links are Ready, or it has timed out with any link Ready.
+func satisfiedTriggerGroup(neu *neuron) (string, []*link, bool) {
+	for group, links := range neu.spec.triggerGroups {
+		if len(links) == 0 {
+			continue
+		}
+		readyLinks := make([]*link, 0, len(links))
 		for _, l := range links {
 			if l.status.state == core.LinkStateReady {
-				trigLinks = append(trigLinks, l)
-			} else {
-				break
+				readyLinks = append(readyLinks, l)
 			}
 		}
-		if len(links) != 0 && len(trigLinks) == len(links) {
+		if len(readyLinks) == 0 {
+			continue
+		}
+
+		spec := neu.spec.triggerGroupSpecs[group]
+		quorum := len(links)
+		if spec.Quorum > 0 && spec.Quorum < quorum {
+			quorum = spec.Quorum
+		}
+		if len(readyLinks) >= quorum || neu.status.triggerTimeouts[group].expired {
+			return group, readyLinks, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// consumeTriggerGroup sets the Ready links of the fired trigger group init, other in-links keep their state,
+// so that casts arrive while processing are not lost. Links of the group which are not Ready yet are late
+// if their source neurons are being processed.
+// It returns the payloads of the consumed links as the inputs of activation.
+func (b *BrainLite) consumeTriggerGroup(n *neuron, group string, readyLinks []*link) []linkInput {
+	consumed := make(map[string]struct{}, len(readyLinks))
+	inputs := make([]linkInput, 0, len(readyLinks))
+	for _, l := range readyLinks {
+		inputs = append(inputs, linkInput{linkID: l.id, from: l.spec.from, payload: l.status.payload, limited: l.status.limited})
+		l.status.state = core.LinkStateInit
+		l.status.payload = nil
+		l.status.limited = false
+		consumed[l.id] = struct{}{}
+	}
+	for _, l := range n.spec.triggerGroups[group] {
+		if _, ok := consumed[l.id]; ok {
+			continue
+		}
+		// only the cast of activation being processed is late, source neuron which is not activated casts
+		// in a later iteration
+		if src, ok := b.neurons[l.spec.from]; ok && src.status.state == core.NeuronStateActivated {
+			l.status.lateSeq = src.status.seq
+		}
+	}
+	delete(n.status.triggerTimeouts, group)
+
+	return inputs
+}
+
+// limitRouted reports whether any input is cast by limit cast group
+func limitRouted(inputs []linkInput) bool {
+	for _, in := range inputs {
+		if in.limited {
 			return true
 		}
 	}
@@ -290,6 +678,86 @@ func (b *BrainLite) ifNeuronShouldActivate(neu *neuron) bool {
 	return false
 }
 
+// startTriggerTimeouts starts timer for trigger groups with timeout which are partially Ready
+func (b *BrainLite) startTriggerTimeouts(n *neuron) {
+	for group, spec := range n.spec.triggerGroupSpecs {
+		if spec.Timeout <= 0 {
+			continue
+		}
+		if _, ok := n.status.triggerTimeouts[group]; ok { // timer started
+			continue
+		}
+		var ready bool
+		for _, l := range n.spec.triggerGroups[group] {
+			if l.status.state == core.LinkStateReady {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		n.status.triggerTimeoutGen++
+		id := n.status.triggerTimeoutGen
+		n.status.triggerTimeouts[group] = triggerTimeout{id: id}
+		time.AfterFunc(spec.Timeout, func() {
+			b.publishEvent(maintainEvent{
+				kind:   eventKindNeuron,
+				action: eventActionNeuronTriggerTimeout,
+				id:     n.id,
+				seq:    id,
+			})
+		})
+	}
+}
+
+// triggerGroupTimeout expires the trigger group and tries to activate neuron with whatever is Ready
+func (b *BrainLite) triggerGroupTimeout(n *neuron, id int) error {
+	for group, timeout := range n.status.triggerTimeouts {
+		if timeout.id != id {
+			continue
+		}
+		b.logger.Debug().Str("neuronID", n.id).Str("group", group).Msg("trigger group timeout")
+		timeout.expired = true
+		n.status.triggerTimeouts[group] = timeout
+
+		return b.tryActivateNeuron(n)
+	}
+
+	return nil
+}
+
+// retriggerNeuron deals with the trigger which arrives while neuron is active by its RetriggerPolicy,
+// it returns true if neuron should be activated again right now.
+func (b *BrainLite) retriggerNeuron(n *neuron) bool {
+	group, links, ok := satisfiedTriggerGroup(n)
+	if !ok {
+		b.logger.Debug().Str("neuronID", n.id).Msg("neuron already activated")
+		return false
+	}
+
+	switch n.spec.retrigger {
+	case core.RetriggerIgnore:
+		b.logger.Debug().Str("neuronID", n.id).Msg("neuron already activated, ignore the trigger")
+		b.consumeTriggerGroup(n, group, links)
+		return false
+	case core.RetriggerRestart:
+		b.logger.Debug().Str("neuronID", n.id).Int("seq", n.status.seq).Msg("neuron already activated, cancel and restart")
+		// result of the canceled process is stale and will be dropped
+		if n.status.cancel != nil {
+			n.status.cancel()
+			n.status.cancel = nil
+		}
+		n.status.count.canceled++
+		return true
+	default:
+		// keep in-links Ready, trigger groups are re-checked after processed
+		b.logger.Debug().Str("neuronID", n.id).Msg("neuron already activated, queue the trigger")
+		return false
+	}
+}
+
 func (b *BrainLite) refreshState() {
 	inactiveCnt, activateCnt := b.getNeuronCountByState()
 	initCnt, waitCnt, readyCnt := b.getLinkCountByState()
@@ -301,13 +769,8 @@ func (b *BrainLite) refreshState() {
 		Int("linkWait", waitCnt).
 		Int("linkReady", readyCnt).
 		Msg("refresh brain state by count")
-	// send brain sleep message
 	if activateCnt+waitCnt+readyCnt == 0 {
-		b.publishEvent(maintainEvent{
-			kind:   eventKindBrain,
-			action: eventActionBrainSleep,
-			id:     b.id,
-		})
+		b.forceSleep()
 	} else { // > 0, set to running
 		b.setState(core.BrainStateRunning)
 	}
@@ -343,18 +806,54 @@ func (b *BrainLite) getLinkCountByState() (int, int, int) {
 	return initCnt, waitCnt, readyCnt
 }
 
+// ForceSleep resets all neurons and links, and sets brain sleeping. It blocks until maintainer handled it.
 func (b *BrainLite) ForceSleep() {
+	b.publishEventAndWait(maintainEvent{
+		kind:   eventKindBrain,
+		action: eventActionBrainSleep,
+		id:     b.id,
+	})
+}
+
+// forceSleep should only be called by maintainer
+func (b *BrainLite) forceSleep() {
 	for _, l := range b.links {
 		l.status.state = core.LinkStateInit
+		l.status.payload = nil
+		l.status.pendingCasts = nil
+		l.status.lateSeq = 0
+		l.status.limited = false
 	}
 	for _, neu := range b.neurons {
 		neu.status.state = core.NeuronStateInactive
+		neu.status.inputs = nil
+		neu.status.output = nil
+		neu.status.triggerTimeouts = make(map[string]triggerTimeout)
+		neu.status.castRetry.attempts = 0
+		neu.status.castRetry.scheduled = false
 	}
 	b.setState(core.BrainStateSleeping)
 }
 
+// endOutcome returns the outcome of the first end link with outcome in links
+func endOutcome(links []*link) string {
+	for _, l := range links {
+		if l.spec.outcome != "" {
+			return l.spec.outcome
+		}
+	}
+
+	return ""
+}
+
 func (b *BrainLite) setState(state core.BrainState) {
 	b.mu.Lock()
+	if state == core.BrainStateRunning && b.state != core.BrainStateRunning { // a new run starts
+		b.runID = utils.GenID()
+		b.runErr = nil
+		b.runLimit = nil
+		b.runOutcome = ""
+	}
 	b.state = state
 	b.cond.Broadcast() // Notify all waiting goroutines
 	b.mu.Unlock()
@@ -365,3 +864,41 @@ func (b *BrainLite) getState() core.BrainState {
 	defer b.mu.Unlock()
 	return b.state
 }
+
+func (b *BrainLite) getRunID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runID
+}
+
+func (b *BrainLite) isClosing() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closing
+}
+
+// waitUntil blocks until cond returns true or ctx is done, cond is called with b.mu locked
+func (b *BrainLite) waitUntil(ctx context.Context, cond func() bool) error {
+	var canceled bool // guarded by b.mu
+	done := make(chan struct{})
+	go func() {
+		b.mu.Lock()
+		for !canceled && !cond() {
+			b.cond.Wait()
+		}
+		b.mu.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		canceled = true
+		b.cond.Broadcast()
+		b.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
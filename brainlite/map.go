@@ -0,0 +1,234 @@
+package brainlite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/zenmodel/zenmodel/core"
+)
+
+// mapTask is one activation of map neuron, its items are processed by neuron workers in parallel,
+// the last finished item stores the results and publishes the processed event.
+type mapTask struct {
+	neuronID string
+	seq      int
+	// inputs is the payloads of in-links which triggered map neuron, shared by all items
+	inputs []linkInput
+
+	mu        sync.Mutex
+	remaining int
+	results   []any
+	err       error
+}
+
+// mapItem is one item of map task
+type mapItem struct {
+	task  *mapTask
+	index int
+	value any
+}
+
+// mapScope is the scoped memory of one item, it is only visible to the process of the item
+type mapScope struct {
+	item *mapItem
+	mu   sync.RWMutex
+	// memories is keyed by indexMemoryKey, value of memoryWrite is the memory with its original key
+	memories map[any]memoryWrite
+}
+
+// activateMapNeuron reads the list from memory and pushes its items to the worker pool of neuron
+func (b *BrainLite) activateMapNeuron(ctx context.Context, neu *neuron, seq int, inputs []linkInput) error {
+	list, err := toList(b.GetMemory(neu.spec.mapList))
+	if err != nil {
+		err = fmt.Errorf("process neuron error: %w", err)
+		b.publishEvent(maintainEvent{
+			kind:   eventKindNeuron,
+			action: eventActionNeuronProcessed,
+			id:     neu.id,
+			seq:    seq,
+			err:    err,
+		})
+		return err
+	}
+
+	task := &mapTask{
+		neuronID:  neu.id,
+		seq:       seq,
+		inputs:    inputs,
+		remaining: len(list),
+		results:   make([]any, len(list)),
+	}
+	if len(list) == 0 {
+		b.mapTaskDone(ctx, neu, task)
+		return nil
+	}
+
+	b.logger.Debug().Str("neuronID", neu.id).Int("seq", seq).Int("items", len(list)).Msg("start map neuron")
+	for i, v := range list {
+		item := &mapItem{task: task, index: i, value: v}
+		b.mu.Lock()
+		ok := b.nQueues != nil && b.pushActivation(neu, activation{neuronID: neu.id, seq: seq, ctx: ctx, item: item})
+		b.mu.Unlock()
+		if !ok {
+			b.mapItemDone(ctx, neu, item, nil, core.ErrBrainShuttingDown)
+		}
+	}
+
+	return nil
+}
+
+// processMapItem runs processor of map neuron for one item with scoped memory
+func (b *BrainLite) processMapItem(ctx context.Context, neu *neuron, item *mapItem) {
+	if err := ctx.Err(); err != nil {
+		b.mapItemDone(ctx, neu, item, nil, err)
+		return
+	}
+
+	scope := &mapScope{item: item, memories: make(map[any]memoryWrite)}
+	err := neu.spec.processor.Clone().Process(&brainContext{
+		Context:         ctx,
+		b:               b,
+		currentNeuronID: neu.id,
+		seq:             item.task.seq,
+		inputs:          item.task.inputs,
+		scope:           scope,
+	})
+	result, _ := scope.get(core.MapResultMemoryKey)
+	b.mapItemDone(ctx, neu, item, result, err)
+}
+
+func (b *BrainLite) mapItemDone(ctx context.Context, neu *neuron, item *mapItem, result any, err error) {
+	task := item.task
+	task.mu.Lock()
+	task.results[item.index] = result
+	if err != nil && task.err == nil {
+		task.err = fmt.Errorf("map item %d error: %w", item.index, err)
+	}
+	task.remaining--
+	last := task.remaining == 0
+	task.mu.Unlock()
+
+	if last {
+		b.mapTaskDone(ctx, neu, task)
+	}
+}
+
+// mapTaskDone stores results to memory and publishes the processed event of map neuron
+func (b *BrainLite) mapTaskDone(ctx context.Context, neu *neuron, task *mapTask) {
+	err := task.err
+	// the activation has been canceled, its results are stale
+	if err == nil && ctx.Err() == nil {
+		err = b.SetMemory(neu.spec.mapResult, task.results)
+	}
+	var cast castDecision
+	if err != nil {
+		err = fmt.Errorf("process neuron error: %w", err)
+	} else {
+		cast = decideCast(neu, &brainContext{
+			Context:         ctx,
+			b:               b,
+			currentNeuronID: neu.id,
+			seq:             task.seq,
+			inputs:          task.inputs,
+		})
+	}
+
+	b.publishEvent(maintainEvent{
+		kind:    eventKindNeuron,
+		action:  eventActionNeuronProcessed,
+		id:      task.neuronID,
+		seq:     task.seq,
+		err:     err,
+		payload: task.results,
+		cast:    cast,
+	})
+}
+
+// toList converts slice or array to []any, nil is an empty list
+func toList(v any) ([]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if list, ok := v.([]any); ok {
+		return list, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("map list should be slice or array, got %T", v)
+	}
+	list := make([]any, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+
+	return list, nil
+}
+
+func (s *mapScope) get(key any) (any, bool) {
+	switch key {
+	case core.MapItemMemoryKey:
+		return s.item.value, true
+	case core.MapIndexMemoryKey:
+		return s.item.index, true
+	}
+	k, err := indexMemoryKey(key)
+	if err != nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.memories[k]
+
+	return m.value, ok
+}
+
+// list returns a snapshot of scoped memories, including the item and index of map item
+func (s *mapScope) list() []memoryWrite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	memories := make([]memoryWrite, 0, len(s.memories)+2)
+	memories = append(memories,
+		memoryWrite{key: core.MapItemMemoryKey, value: s.item.value},
+		memoryWrite{key: core.MapIndexMemoryKey, value: s.item.index})
+	for _, m := range s.memories {
+		memories = append(memories, m)
+	}
+
+	return memories
+}
+
+// set sets memory of key which is checked by indexMemoryKey
+func (s *mapScope) set(key, value any) {
+	k, _ := indexMemoryKey(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memories[k] = memoryWrite{key: scopeKey(key), value: value}
+}
+
+func (s *mapScope) del(key any) {
+	k, err := indexMemoryKey(key)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.memories, k)
+}
+
+func (s *mapScope) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memories = make(map[any]memoryWrite)
+}
+
+// scopeKey makes []byte key comparable, it is the same memory key as string in brain memory
+func scopeKey(key any) any {
+	if k, ok := key.([]byte); ok {
+		return string(k)
+	}
+
+	return key
+}
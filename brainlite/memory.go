@@ -1,160 +1,440 @@
 package brainlite
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"math"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 
+	"github.com/zenmodel/zenmodel/core"
 	"github.com/zenmodel/zenmodel/internal/errors"
+	"github.com/zenmodel/zenmodel/memory"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type BrainMemory struct {
+	// mu guards the db pointer, db itself is safe for concurrent use
+	mu             sync.RWMutex
 	db             *sql.DB
 	datasourceName string
 	// 是否在 brain Shutdown 时保留数据库文件
-	keepMemory     bool
+	keepMemory bool
+	// reducers of memory key, they are not changed after build
+	reducers map[string]core.Reducer
+	// txMu serializes writes of memory, writes of a transaction are committed in one SQLite transaction
+	txMu sync.Mutex
+	// watchers receive changes of memory
+	watchers memoryWatchers
 }
 
+func (m *BrainMemory) Init() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db != nil {
+		return nil
+	}
 
-func (m *BrainMemory)Init() error {
 	db, err := sql.Open("sqlite3", m.datasourceName)
 	if err != nil {
 		return errors.Wrapf(err, "init memory failed")
 	}
 	m.db = db
 
-	// 创建 memory 表
+	// 创建 memory 表, key 是原始 key 的哈希, raw_key 和 key_type 按值的编码方式保存原始 key, 用于列出 key
 	_, err = m.db.Exec(`CREATE TABLE IF NOT EXISTS memory (
 		key INTEGER PRIMARY KEY,
 		value JSON,
-		type TEXT
+		type TEXT,
+		raw_key JSON,
+		key_type TEXT
 	)`)
 	if err != nil {
 		return errors.Wrapf(err, "init memory table failed")
 	}
+	// 保留的旧数据库文件没有原始 key 列
+	if err = addColumns(m.db, "memory", "raw_key JSON", "key_type TEXT"); err != nil {
+		return errors.Wrapf(err, "init memory table failed")
+	}
+	// memory_writer 记录当前事务的写入者, 由 changelog 触发器读取
+	_, err = m.db.Exec(`CREATE TABLE IF NOT EXISTS memory_writer (
+		id INTEGER PRIMARY KEY,
+		writer TEXT
+	)`)
+	if err != nil {
+		return errors.Wrapf(err, "init memory writer table failed")
+	}
+	// 清理上次未关闭的 changelog, 例如保留的数据库文件
+	if err = dropChangelog(m.db); err != nil {
+		return errors.Wrapf(err, "init memory changelog failed")
+	}
 
 	return nil
 }
 
-func (m *BrainMemory)Set(key, value any) error {
-	var valueType string
-	var valueJSON []byte
-	var err error
+func (m *BrainMemory) IsInit() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	hashedKey, err := hashKey(key)
-	if err != nil {
-		return fmt.Errorf("无法哈希键: %v", err)
-	}
+	return m.db != nil
+}
 
-	switch value.(type) {
-	case string:
-		valueType = "string"
-	case int, int32, int64, uint32:
-		valueType = "int"
-	case float64:
-		valueType = "float"
-	case bool:
-		valueType = "bool"
-	default:
-		valueType = "json"
+func (m *BrainMemory) getDB() (*sql.DB, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.db == nil {
+		return nil, fmt.Errorf("memory not initialized")
 	}
 
-	valueJSON, err = json.Marshal(value)
+	return m.db, nil
+}
+
+func (m *BrainMemory) Set(key, value any) error {
+	return m.Apply([]memoryWrite{{key: key, value: value}}, "")
+}
+
+// Apply applies writes in one SQLite transaction, readers see all or none of them.
+// writer is the neuron ID recorded in changelog
+func (m *BrainMemory) Apply(writes []memoryWrite, writer string) (err error) {
+	db, err := m.getDB()
 	if err != nil {
-		return fmt.Errorf("无法序列化值: %v", err)
+		return err
 	}
 
-	_, err = m.db.Exec("INSERT OR REPLACE INTO memory (key, value, type) VALUES (?, ?, ?)",
-		hashedKey, valueJSON, valueType)
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("存储数据时出错: %v", err)
+		return fmt.Errorf("开启事务时出错: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = applyWrites(context.Background(), tx, writes, writer); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务时出错: %v", err)
 	}
 
 	return nil
 }
 
-func (m *BrainMemory)Get(key any) (any, error) {
-	hashedKey, err 	:= hashKey(key)
+// Update reads memory by lookup and applies the writes returned by fn in one SQLite transaction.
+// The transaction is started by BEGIN IMMEDIATE, so other processes (e.g. Python processors) can not write memory
+// between the reads and the writes. writer is the neuron ID recorded in changelog
+func (m *BrainMemory) Update(writer string, fn func(lookup memoryLookup) ([]memoryWrite, error)) (err error) {
+	db, err := m.getDB()
 	if err != nil {
-		return nil, fmt.Errorf("无法哈希键: %v", err)
+		return err
 	}
 
-	var valueJSON []byte
-	var valueType string
-	err = m.db.QueryRow("SELECT value, type FROM memory WHERE key = ?", hashedKey).Scan(&valueJSON, &valueType)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("未找到键 '%v'", key)
+		return fmt.Errorf("开启事务时出错: %v", err)
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("开启事务时出错: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_, _ = conn.ExecContext(ctx, "ROLLBACK")
+		}
+	}()
+
+	writes, err := fn(func(key any) (any, bool, error) {
+		return queryMemory(ctx, conn, key)
+	})
+	if err != nil {
+		return err
+	}
+	if len(writes) > 0 {
+		if err = applyWrites(ctx, conn, writes, writer); err != nil {
+			return err
 		}
-		return nil, fmt.Errorf("查询数据时出错: %v", err)
 	}
+	if _, err = conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("提交事务时出错: %v", err)
+	}
+
+	return nil
+}
+
+// memoryLookup gets value of key in a SQLite transaction, see BrainMemory.Update
+type memoryLookup func(key any) (value any, found bool, err error)
+
+// sqlExecer is *sql.Tx or *sql.Conn in transaction
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// sqlQueryer is *sql.DB or *sql.Conn in transaction
+type sqlQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	var value any
-	switch valueType {
-	case "string":
-		err = json.Unmarshal(valueJSON, &value)
-	case "int":
-		var intValue int64
-		err = json.Unmarshal(valueJSON, &intValue)
+// applyWrites writes memories and the writer recorded in changelog, it should be called in a SQLite transaction
+func applyWrites(ctx context.Context, tx sqlExecer, writes []memoryWrite, writer string) error {
+	if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO memory_writer (id, writer) VALUES (1, ?)", writer); err != nil {
+		return fmt.Errorf("存储写入者时出错: %v", err)
+	}
+	for _, w := range writes {
+		hashedKey, err := hashKey(w.key)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("无法哈希键: %v", err)
 		}
-		// 根据数值范围选择合适的类型
-		switch {
-		case intValue >= int64(math.MinInt32) && intValue <= int64(math.MaxInt32):
-			value = int(intValue)
-		default:
-			value = intValue
+		if w.del {
+			if _, err = tx.ExecContext(ctx, "DELETE FROM memory WHERE key = ?", hashedKey); err != nil {
+				return fmt.Errorf("删除数据时出错: %v", err)
+			}
+			continue
+		}
+
+		rawKey, keyType, err := memory.EncodeValue(scopeKey(w.key))
+		if err != nil {
+			return err
+		}
+		valueJSON, valueType, err := memory.EncodeValue(w.value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT OR REPLACE INTO memory (key, value, type, raw_key, key_type) VALUES (?, ?, ?, ?, ?)",
+			hashedKey, valueJSON, valueType, rawKey, keyType)
+		if err != nil {
+			return fmt.Errorf("存储数据时出错: %v", err)
 		}
-	case "float":
-		var floatValue float64
-		err = json.Unmarshal(valueJSON, &floatValue)
-		value = floatValue
-	case "bool":
-		var boolValue bool
-		err = json.Unmarshal(valueJSON, &boolValue)
-		value = boolValue
-	case "json":
-		err = json.Unmarshal(valueJSON, &value)
 	}
 
+	return nil
+}
+
+func (m *BrainMemory) Get(key any) (any, error) {
+	value, found, err := m.Lookup(key)
 	if err != nil {
-		return nil, fmt.Errorf("解析数据时出错: %v", err)
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("未找到键 '%v'", key)
 	}
 
 	return value, nil
 }
 
-func (m *BrainMemory)Del(key any) error {
+// Lookup gets value of key, found is false if key not exists
+func (m *BrainMemory) Lookup(key any) (value any, found bool, err error) {
+	db, err := m.getDB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return queryMemory(context.Background(), db, key)
+}
+
+// queryMemory gets value of key by db, or by connection in a SQLite transaction
+func queryMemory(ctx context.Context, db sqlQueryer, key any) (value any, found bool, err error) {
 	hashedKey, err := hashKey(key)
 	if err != nil {
-		return fmt.Errorf("无法哈希键: %v", err)
+		return nil, false, fmt.Errorf("无法哈希键: %v", err)
+	}
+
+	var valueJSON []byte
+	var valueType string
+	err = db.QueryRowContext(ctx, "SELECT value, type FROM memory WHERE key = ?", hashedKey).Scan(&valueJSON, &valueType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("查询数据时出错: %v", err)
 	}
 
-	_, err = m.db.Exec("DELETE FROM memory WHERE key = ?", hashedKey)
+	value, err = memory.DecodeValue(valueJSON, valueType)
 	if err != nil {
-		return fmt.Errorf("删除数据时出错: %v", err)
+		return nil, false, err
 	}
 
-	return nil
+	return value, true, nil
+}
+
+// Decode decodes the stored JSON of key into target directly, so that typed values keep their types,
+// e.g. structs and int64. ok is false if key not found.
+func (m *BrainMemory) Decode(key any, target any) (bool, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return false, err
+	}
+
+	hashedKey, err := hashKey(key)
+	if err != nil {
+		return false, fmt.Errorf("无法哈希键: %v", err)
+	}
+
+	var valueJSON []byte
+	err = db.QueryRow("SELECT value FROM memory WHERE key = ?", hashedKey).Scan(&valueJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("查询数据时出错: %v", err)
+	}
+	if err = json.Unmarshal(valueJSON, target); err != nil {
+		return true, fmt.Errorf("解析数据时出错: %v", err)
+	}
+
+	return true, nil
 }
 
-func (m *BrainMemory)Clear() error {
-	_, err := m.db.Exec("DELETE FROM memory")
+func (m *BrainMemory) Del(key any) error {
+	return m.Apply([]memoryWrite{{key: key, del: true}}, "")
+}
+
+// Clear deletes all memories, writer is the neuron ID recorded in changelog
+func (m *BrainMemory) Clear(writer string) (err error) {
+	db, err := m.getDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
+		return fmt.Errorf("开启事务时出错: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec("INSERT OR REPLACE INTO memory_writer (id, writer) VALUES (1, ?)", writer); err != nil {
+		return fmt.Errorf("存储写入者时出错: %v", err)
+	}
+	if _, err = tx.Exec("DELETE FROM memory"); err != nil {
 		return fmt.Errorf("清空数据时出错: %v", err)
 	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务时出错: %v", err)
+	}
 
 	return nil
 }
 
-func (m *BrainMemory)Close() error {
+func (m *BrainMemory) Len() (int, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err = db.QueryRow("SELECT COUNT(*) FROM memory").Scan(&n); err != nil {
+		return 0, fmt.Errorf("统计数据时出错: %v", err)
+	}
+
+	return n, nil
+}
+
+// Keys lists keys of all memories, see decodeKey
+func (m *BrainMemory) Keys() ([]any, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT key, raw_key, key_type FROM memory")
+	if err != nil {
+		return nil, fmt.Errorf("查询数据时出错: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []any
+	for rows.Next() {
+		var hashedKey int64
+		var rawKey []byte
+		var keyType sql.NullString
+		if err = rows.Scan(&hashedKey, &rawKey, &keyType); err != nil {
+			return nil, fmt.Errorf("查询数据时出错: %v", err)
+		}
+		key, err := decodeKey(hashedKey, rawKey, keyType)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询数据时出错: %v", err)
+	}
+
+	return keys, nil
+}
+
+// List returns all memories, rows are read before return so that caller may write memory while iterating them
+func (m *BrainMemory) List() ([]memoryWrite, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT key, raw_key, key_type, value, type FROM memory")
+	if err != nil {
+		return nil, fmt.Errorf("查询数据时出错: %v", err)
+	}
+	defer rows.Close()
+
+	var memories []memoryWrite
+	for rows.Next() {
+		var hashedKey int64
+		var rawKey, valueJSON []byte
+		var keyType sql.NullString
+		var valueType string
+		if err = rows.Scan(&hashedKey, &rawKey, &keyType, &valueJSON, &valueType); err != nil {
+			return nil, fmt.Errorf("查询数据时出错: %v", err)
+		}
+		var w memoryWrite
+		if w.key, err = decodeKey(hashedKey, rawKey, keyType); err != nil {
+			return nil, err
+		}
+		if w.value, err = memory.DecodeValue(valueJSON, valueType); err != nil {
+			return nil, err
+		}
+		memories = append(memories, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询数据时出错: %v", err)
+	}
+
+	return memories, nil
+}
+
+// Size returns the total length of JSON encoded values
+func (m *BrainMemory) Size() (int64, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err = db.QueryRow("SELECT COALESCE(SUM(LENGTH(value)), 0) FROM memory").Scan(&n); err != nil {
+		return 0, fmt.Errorf("统计数据时出错: %v", err)
+	}
+
+	return n, nil
+}
+
+func (m *BrainMemory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return nil
+	}
+
 	if err := m.db.Close(); err != nil {
 		return err
 	}
@@ -166,22 +446,117 @@ func (m *BrainMemory)Close() error {
 		}
 	}
 
-
 	return nil
-}	
+}
 
 // hashKey 将任意类型的 key 转换为 int64
 func hashKey(key any) (int64, error) {
-    switch key.(type) {
-    case int, int32, int64, uint32, uint64, float64, string, []byte, byte:
-        // 继续处理
-    default:
-        return 0, fmt.Errorf("unsupported key type %T", key)
-    }
-
-    h := sha256.New()
-    h.Write([]byte(fmt.Sprintf("%v", key)))
-    hashBytes := h.Sum(nil)
-    // 取前8个字节并转换为 int64
-    return int64(binary.BigEndian.Uint64(hashBytes[:8])), nil
-}
\ No newline at end of file
+	text, err := keyText(key)
+	if err != nil {
+		return 0, err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(text))
+	hashBytes := h.Sum(nil)
+	// 取前8个字节并转换为 int64
+	return int64(binary.BigEndian.Uint64(hashBytes[:8])), nil
+}
+
+// keyText is the text of key which is hashed as primary key of memory. Strings, bools and numbers are formatted
+// by %v, []byte is the same key as string, other keys are "json:" followed by their canonical JSON encoding, whose
+// object fields are sorted by name, so that struct key and the map[string]any decoded from it are the same key.
+// Python BrainContext hashes keys in the same way
+func keyText(key any) (string, error) {
+	if k, ok := key.([]byte); ok {
+		return string(k), nil
+	}
+	switch reflect.ValueOf(key).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%v", key), nil
+	case reflect.Invalid:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	data, err := canonicalJSON(key)
+	if err != nil {
+		return "", fmt.Errorf("unsupported key type %T: %v", key, err)
+	}
+
+	return "json:" + string(data), nil
+}
+
+// canonicalJSON encodes v as JSON whose object fields are sorted, struct fields are in declaration order when
+// marshaled, so it is decoded to map and marshaled again. Numbers are kept as they are encoded
+func canonicalJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.ContainsRune(data, '{') {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded any
+	if err = dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
+
+// indexMemoryKey makes the key of memory in maps, e.g. writes of transaction and watched keys. It is the text of
+// key hashed in SQLite, so that JSON objects listed by ListMemoryKeys are the same keys as the structs they decode from
+func indexMemoryKey(key any) (any, error) {
+	return keyText(key)
+}
+
+// decodeKey decodes the original key stored with memory, structs are decoded as map[string]any.
+// It returns the hashed key if original key is not stored, e.g. memory written by old versions
+func decodeKey(hashedKey int64, rawKey []byte, keyType sql.NullString) (any, error) {
+	if !keyType.Valid {
+		return hashedKey, nil
+	}
+
+	return memory.DecodeValue(rawKey, keyType.String)
+}
+
+// addColumns adds columns to table if they do not exist
+func addColumns(db *sql.DB, table string, columns ...string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		name := strings.Fields(column)[0]
+		if existing[name] {
+			continue
+		}
+		if _, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package brainlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/memory"
+)
+
+const defaultMemoryWatchInterval = 100 * time.Millisecond
+
+// changelog 由 memory 表上的触发器写入, 因此 Python Processor 直接写入 SQLite 文件的变更也会被 watch 到.
+// 写入者从同一事务中写入的 memory_writer 读取
+var createChangelogSQLs = []string{
+	`CREATE TABLE IF NOT EXISTS memory_changelog (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		key INTEGER,
+		raw_key JSON,
+		key_type TEXT,
+		old_value JSON,
+		old_type TEXT,
+		new_value JSON,
+		new_type TEXT,
+		deleted INTEGER,
+		writer TEXT
+	)`,
+	// INSERT OR REPLACE 不会触发 DELETE 触发器, 所以在插入前读取旧值
+	`CREATE TRIGGER IF NOT EXISTS memory_changelog_insert BEFORE INSERT ON memory
+	BEGIN
+		INSERT INTO memory_changelog (key, raw_key, key_type, old_value, old_type, new_value, new_type, deleted, writer)
+		VALUES (NEW.key, NEW.raw_key, NEW.key_type,
+			(SELECT value FROM memory WHERE key = NEW.key),
+			(SELECT type FROM memory WHERE key = NEW.key),
+			NEW.value, NEW.type, 0,
+			(SELECT writer FROM memory_writer WHERE id = 1));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS memory_changelog_update AFTER UPDATE ON memory
+	BEGIN
+		INSERT INTO memory_changelog (key, raw_key, key_type, old_value, old_type, new_value, new_type, deleted, writer)
+		VALUES (NEW.key, NEW.raw_key, NEW.key_type, OLD.value, OLD.type, NEW.value, NEW.type, 0,
+			(SELECT writer FROM memory_writer WHERE id = 1));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS memory_changelog_delete AFTER DELETE ON memory
+	BEGIN
+		INSERT INTO memory_changelog (key, raw_key, key_type, old_value, old_type, new_value, new_type, deleted, writer)
+		VALUES (OLD.key, OLD.raw_key, OLD.key_type, OLD.value, OLD.type, NULL, NULL, 1,
+			(SELECT writer FROM memory_writer WHERE id = 1));
+	END`,
+}
+
+var dropChangelogSQLs = []string{
+	`DROP TRIGGER IF EXISTS memory_changelog_insert`,
+	`DROP TRIGGER IF EXISTS memory_changelog_update`,
+	`DROP TRIGGER IF EXISTS memory_changelog_delete`,
+	`DROP TABLE IF EXISTS memory_changelog`,
+}
+
+// changelogWatch polls memory_changelog while there are watchers, and sends the changes to them
+type changelogWatch struct {
+	mu       sync.Mutex
+	running  bool
+	stop     chan struct{}
+	cursor   int64
+	interval time.Duration
+	// kick wakes up the poller after memory is written by brain
+	kick chan struct{}
+
+	// keys maps hashed key to the watched or written memory key, so that changes are sent with the key as it is
+	// given, e.g. int64 rather than the decoded int
+	keys   map[int64]any
+	keysMu sync.Mutex
+}
+
+type changelogEntry struct {
+	seq      int64
+	key      int64
+	rawKey   any
+	old      any
+	new      any
+	deleted  bool
+	neuronID string
+}
+
+// WatchMemory watches changes of memory keys, or all memories if no key is given, see core.Brain.
+// Changes written to SQLite file by other processes, e.g. Python processors, are sent too.
+func (b *BrainLite) WatchMemory(ctx context.Context, keys ...any) (<-chan core.MemoryChange, error) {
+	b.changelog.mu.Lock()
+	defer b.changelog.mu.Unlock()
+
+	w, err := b.BrainMemory.watchers.add(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("watch memory: %w", err)
+	}
+	b.learnMemoryKeys(keys...)
+	if b.changelog.running {
+		return w.ch, nil
+	}
+	if err = b.startChangelogWatch(); err != nil {
+		b.BrainMemory.watchers.remove(w)
+		return nil, fmt.Errorf("watch memory: %w", err)
+	}
+
+	return w.ch, nil
+}
+
+// learnMemoryKeys records memory keys of hashed keys, see changelogWatch.keys
+func (b *BrainLite) learnMemoryKeys(keys ...any) {
+	b.changelog.keysMu.Lock()
+	defer b.changelog.keysMu.Unlock()
+	for _, k := range keys {
+		hashedKey, err := hashKey(k)
+		if err != nil {
+			continue
+		}
+		if bs, ok := k.([]byte); ok {
+			k = string(bs)
+		}
+		b.changelog.keys[hashedKey] = k
+	}
+}
+
+// memoryKeyOf returns the learned key of hashed key, or the original key stored in changelog
+func (b *BrainLite) memoryKeyOf(e changelogEntry) any {
+	b.changelog.keysMu.Lock()
+	defer b.changelog.keysMu.Unlock()
+	if k, ok := b.changelog.keys[e.key]; ok {
+		return k
+	}
+
+	return e.rawKey
+}
+
+// kickChangelogWatch wakes up the poller without waiting for the next interval
+func (b *BrainLite) kickChangelogWatch() {
+	select {
+	case b.changelog.kick <- struct{}{}:
+	default:
+	}
+}
+
+// startChangelogWatch should be called with b.changelog.mu locked
+func (b *BrainLite) startChangelogWatch() error {
+	if err := b.ensureMemoryInit(); err != nil {
+		return err
+	}
+	db, err := b.BrainMemory.getDB()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range createChangelogSQLs {
+		if _, err = db.Exec(stmt); err != nil {
+			return fmt.Errorf("创建 changelog 时出错: %v", err)
+		}
+	}
+	if err = db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM memory_changelog").Scan(&b.changelog.cursor); err != nil {
+		return fmt.Errorf("查询 changelog 时出错: %v", err)
+	}
+
+	b.changelog.running = true
+	b.changelog.stop = make(chan struct{})
+	go b.pollChangelog(b.changelog.stop)
+
+	return nil
+}
+
+// stopChangelogWatch should be called with b.changelog.mu locked
+func (b *BrainLite) stopChangelogWatch() {
+	if !b.changelog.running {
+		return
+	}
+	b.changelog.running = false
+	close(b.changelog.stop)
+	if db, err := b.BrainMemory.getDB(); err == nil {
+		if err = dropChangelog(db); err != nil {
+			b.logger.Error().Err(err).Msg("drop memory changelog failed")
+		}
+	}
+}
+
+func (b *BrainLite) pollChangelog(stop chan struct{}) {
+	ticker := time.NewTicker(b.changelog.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.changelog.kick:
+		case <-stop:
+			return
+		}
+		b.dispatchChangelog()
+	}
+}
+
+// dispatchChangelog sends the new entries of changelog to watchers, and stops watch if there is no watcher
+func (b *BrainLite) dispatchChangelog() {
+	b.changelog.mu.Lock()
+	defer b.changelog.mu.Unlock()
+	if !b.changelog.running {
+		return
+	}
+	if b.BrainMemory.watchers.len() == 0 {
+		b.stopChangelogWatch()
+		return
+	}
+	db, err := b.BrainMemory.getDB()
+	if err != nil {
+		return
+	}
+
+	entries, err := readChangelog(db, b.changelog.cursor)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("read memory changelog failed")
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	for _, e := range entries {
+		b.BrainMemory.watchers.publish(core.MemoryChange{
+			Key:      b.memoryKeyOf(e),
+			Old:      e.old,
+			New:      e.new,
+			Deleted:  e.deleted,
+			NeuronID: e.neuronID,
+		})
+	}
+	b.changelog.cursor = entries[len(entries)-1].seq
+	if _, err = db.Exec("DELETE FROM memory_changelog WHERE seq <= ?", b.changelog.cursor); err != nil {
+		b.logger.Error().Err(err).Msg("prune memory changelog failed")
+	}
+}
+
+func readChangelog(db *sql.DB, after int64) ([]changelogEntry, error) {
+	rows, err := db.Query(`SELECT seq, key, raw_key, key_type, old_value, old_type, new_value, new_type, deleted, writer
+		FROM memory_changelog WHERE seq > ? ORDER BY seq`, after)
+	if err != nil {
+		return nil, fmt.Errorf("查询 changelog 时出错: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []changelogEntry
+	for rows.Next() {
+		var e changelogEntry
+		var rawKey, oldValue, newValue []byte
+		var keyType, oldType, newType, writer sql.NullString
+		var deleted sql.NullBool
+		if err = rows.Scan(&e.seq, &e.key, &rawKey, &keyType, &oldValue, &oldType, &newValue, &newType, &deleted, &writer); err != nil {
+			return nil, fmt.Errorf("查询 changelog 时出错: %v", err)
+		}
+		if e.rawKey, err = decodeKey(e.key, rawKey, keyType); err != nil {
+			return nil, err
+		}
+		if oldType.Valid {
+			if e.old, err = memory.DecodeValue(oldValue, oldType.String); err != nil {
+				return nil, err
+			}
+		}
+		if newType.Valid {
+			if e.new, err = memory.DecodeValue(newValue, newType.String); err != nil {
+				return nil, err
+			}
+		}
+		e.deleted = deleted.Bool
+		e.neuronID = writer.String
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询 changelog 时出错: %v", err)
+	}
+
+	return entries, nil
+}
+
+func dropChangelog(db *sql.DB) error {
+	for _, stmt := range dropChangelogSQLs {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("删除 changelog 时出错: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package brainlite
+
+import "github.com/zenmodel/zenmodel/memory"
+
+// RegisterType registers the type of value, so that GetMemory returns memories of the type as it is.
+// Registered types are shared by all brains and memory snapshots, see memory.RegisterType
+func RegisterType(value any) {
+	memory.RegisterType(value)
+}
+
+// RegisterTypeName registers the type of value by name, see memory.RegisterTypeName
+func RegisterTypeName(name string, value any) {
+	memory.RegisterTypeName(name, value)
+}
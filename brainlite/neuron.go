@@ -1,6 +1,9 @@
 package brainlite
 
 import (
+	"context"
+	"strconv"
+
 	"github.com/zenmodel/zenmodel/core"
 	"github.com/zenmodel/zenmodel/internal/utils"
 	"github.com/zenmodel/zenmodel/processor"
@@ -17,35 +20,99 @@ type neuronSpec struct {
 	processor processor.Processor
 	// 触发组,触发组是用来控制 Neuron 的触发条件
 	triggerGroups map[string][]*link
+	// 触发组的汇合语义, 未声明的触发组需要所有 link Ready
+	triggerGroupSpecs map[string]core.TriggerGroupSpec
 	// 传播组,传播组是用来控制 Neuron 之间的传播关系
 	castGroups map[string][]*link
 	// 在 neuron 运行成功之后通过 Selector 决定传导到哪一个传播组
 	selector processor.Selector
+	// scheduling priority, activated neuron with higher priority is processed first
+	priority int
+	// name of worker pool which processes the neuron
+	pool string
+	// how ContinueCast retries when out-links are not consumed
+	castRetry core.CastRetryPolicy
+	castMode  core.CastMode
+	// what to do when neuron is triggered while it is active
+	retrigger core.RetriggerPolicy
+	// memory key of the list which map neuron maps over, empty if neuron is not a map neuron
+	mapList string
+	// memory key which map neuron stores the results to
+	mapResult string
+	// max activations in a run, 0 means unlimited
+	maxActivations int
+	// cast group which neuron casts to instead of activation when a loop limit is hit
+	limitCastGroup string
 }
 
 type neuronStatus struct {
 	state core.NeuronState
+	// seq is the sequence of current activation, increased by maintainer when neuron activated
+	seq int
+	// runID is the brain run ID of current activation
+	runID string
+	// runActivations is the activations in run runID
+	runActivations int
+	// cancel cancels the process of current activation
+	cancel context.CancelFunc
+	// inputs is the payloads of in-links which triggered current activation
+	inputs []linkInput
+	// output is the payload set by current activation, carried by out-links when neuron casts
+	output interface{}
+	// triggerTimeouts is the timeout status of trigger groups which are partially Ready, key is group ID
+	triggerTimeouts map[string]triggerTimeout
+	// triggerTimeoutGen generates the ID of trigger timeout
+	triggerTimeoutGen int
+	// castRetry is the retry status of ContinueCast
+	castRetry struct {
+		attempts  int
+		scheduled bool
+	}
 	count struct {
 		process int
 		succeed int
 		failed  int
+		// canceled by RetriggerRestart
+		canceled int
 	}
 }
 
+// triggerTimeout is the timer of trigger group with TriggerGroupSpec.Timeout
+type triggerTimeout struct {
+	// id is generated by neuron, timeout event with a different id is stale
+	id      int
+	expired bool
+}
+
 func newNeuron(n core.Neuron, linkMap map[string]*link) *neuron {
 	neu := &neuron{
 		id:     n.GetID(),
 		labels: utils.LabelsDeepCopy(n.GetLabels()),
 		spec: neuronSpec{
-			processor:     n.GetProcessor(),
-			selector:      n.GetSelector(),
-			triggerGroups: make(map[string][]*link),
-			castGroups:    make(map[string][]*link),
+			processor:         n.GetProcessor(),
+			selector:          n.GetSelector(),
+			triggerGroups:     make(map[string][]*link),
+			triggerGroupSpecs: n.ListTriggerGroupSpecs(),
+			castGroups:        make(map[string][]*link),
 		},
 		status: neuronStatus{
-			state: core.NeuronStateInactive,
+			state:           core.NeuronStateInactive,
+			triggerTimeouts: make(map[string]triggerTimeout),
 		},
 	}
+	if p, err := strconv.Atoi(neu.labels[core.NeuronLabelPriority]); err == nil {
+		neu.spec.priority = p
+	}
+	neu.spec.pool = neu.labels[core.NeuronLabelWorkerPool]
+	neu.spec.castRetry = core.CastRetryPolicyFromLabels(neu.labels)
+	neu.spec.castMode = core.CastModeFromLabels(neu.labels)
+	neu.spec.retrigger = core.RetriggerPolicyFromLabels(neu.labels)
+	neu.spec.mapList = neu.labels[core.NeuronLabelMapList]
+	neu.spec.mapResult = neu.labels[core.NeuronLabelMapResult]
+	if m, err := strconv.Atoi(neu.labels[core.NeuronLabelMaxActivations]); err == nil && m > 0 {
+		neu.spec.maxActivations = m
+	}
+	neu.spec.limitCastGroup = neu.labels[core.NeuronLabelLimitCastGroup]
 
 	for gName, links := range n.ListTriggerGroups() {
 		neu.spec.triggerGroups[gName] = make([]*link, len(links))
@@ -63,3 +130,29 @@ func newNeuron(n core.Neuron, linkMap map[string]*link) *neuron {
 
 	return neu
 }
+
+// inLinks returns all in-links of neuron without duplicates
+func (n *neuron) inLinks() []*link {
+	return distinctLinks(n.spec.triggerGroups)
+}
+
+// outLinks returns all out-links of neuron without duplicates
+func (n *neuron) outLinks() []*link {
+	return distinctLinks(n.spec.castGroups)
+}
+
+func distinctLinks(groups map[string][]*link) []*link {
+	seen := make(map[string]struct{})
+	links := make([]*link, 0)
+	for _, group := range groups {
+		for _, l := range group {
+			if _, ok := seen[l.id]; ok {
+				continue
+			}
+			seen[l.id] = struct{}{}
+			links = append(links, l)
+		}
+	}
+
+	return links
+}
@@ -1,78 +1,138 @@
 package brainlite
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/zenmodel/zenmodel/core"
 	"github.com/zenmodel/zenmodel/internal/errors"
+	"github.com/zenmodel/zenmodel/internal/queue"
 )
 
-func (b *BrainLite) publishEventActivateNeuron(neuronID string) {
-	if b.getState() == core.BrainStateShutdown || b.nQueue == nil { // 关闭中或没启动
+// activation is one activation of neuron, it is created by maintainer and processed by neuron worker
+type activation struct {
+	neuronID string
+	seq      int
+	// ctx is canceled when brain shutdown or the activation is restarted
+	ctx context.Context
+	// inputs is the payloads of in-links which triggered the activation
+	inputs []linkInput
+	// item is not nil if the activation processes one item of map neuron
+	item *mapItem
+}
+
+func (b *BrainLite) publishEventActivateNeuron(n *neuron) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == core.BrainStateShutdown || b.closing || b.nQueues == nil { // 关闭中或没启动
 		return
 	}
-	b.logger.Debug().Interface("neuronID", neuronID).Msg("publish activate neuron event")
 
-	b.nQueue <- neuronID
+	ctx, cancel := context.WithCancel(b.ctx)
+	if !b.pushActivation(n, activation{neuronID: n.id, seq: n.status.seq, ctx: ctx, inputs: n.status.inputs}) {
+		cancel()
+		return
+	}
+	n.status.cancel = cancel
 }
 
-func (b *BrainLite) runNeuronWorker() {
-	for neuronID := range b.nQueue {
-		neu, ok := b.neurons[neuronID]
+// pushActivation pushes activation to the queue of neuron worker pool, it should be called with b.mu locked
+func (b *BrainLite) pushActivation(n *neuron, act activation) bool {
+	nQueue, ok := b.nQueues[n.spec.pool]
+	if !ok { // pool not configured, use default pool
+		nQueue = b.nQueues[defaultWorkerPool]
+	}
+	b.logger.Debug().
+		Str("neuronID", n.id).
+		Int("seq", act.seq).
+		Int("priority", n.spec.priority).
+		Msg("publish activate neuron event")
+
+	if !nQueue.Push(act, n.spec.priority) {
+		return false
+	}
+	b.inflight++
+
+	return true
+}
+
+func (b *BrainLite) runNeuronWorker(nQueue *queue.PriorityQueue[activation]) {
+	for {
+		act, ok := nQueue.Pop()
 		if !ok {
-			b.logger.Error().Str("neuronID", neuronID).Msg("neuron not found")
+			return
+		}
+		neu, ok := b.neurons[act.neuronID]
+		if !ok {
+			b.logger.Error().Str("neuronID", act.neuronID).Msg("neuron not found")
+			b.activationDone(nQueue)
 			continue
 		}
 
-		err := b.activateNeuron(neu)
+		if act.item != nil {
+			b.processMapItem(act.ctx, neu, act.item)
+			b.activationDone(nQueue)
+			continue
+		}
+		err := b.activateNeuron(act.ctx, neu, act.seq, act.inputs)
 		if err != nil {
-			b.logger.Error().Err(err).Str("neuronID", neuronID).Msg("activate neuron error")
+			b.logger.Error().Err(err).Str("neuronID", act.neuronID).Msg("activate neuron error")
 		}
+		b.activationDone(nQueue)
 	}
 }
 
-func (b *BrainLite) activateNeuron(neu *neuron) error {
-	if neu == nil {
-		return errors.ErrNeuronNotFound("nil")
+// activationDone decreases in-flight activations, workers of a stopped maintainer have no effect
+func (b *BrainLite) activationDone(nQueue *queue.PriorityQueue[activation]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, q := range b.nQueues {
+		if q == nQueue {
+			b.inflight--
+			b.cond.Broadcast()
+			return
+		}
 	}
+}
 
-	b.logger.Debug().Interface("neuronID", neu.id).Msg("start activate neuron")
-	neu.status.state = core.NeuronStateActivated
-	// in-link set init
-	for _, links := range neu.spec.triggerGroups {
-		for _, l := range links {
-			l.status.state = core.LinkStateInit
-		}
+// activateNeuron runs neuron processor, the state of neuron and links has been changed by maintainer
+// before activation, and will be changed by maintainer after the processed event published.
+func (b *BrainLite) activateNeuron(ctx context.Context, neu *neuron, seq int, inputs []linkInput) error {
+	if neu == nil {
+		return errors.ErrNeuronNotFound("nil")
 	}
 
-	// out-link set wait
-	for _, links := range neu.spec.castGroups {
-		for _, l := range links {
-			l.status.state = core.LinkStateWait
-		}
+	if neu.spec.mapList != "" {
+		return b.activateMapNeuron(ctx, neu, seq, inputs)
 	}
 
-	neu.status.count.process++
+	b.logger.Debug().Interface("neuronID", neu.id).Int("seq", seq).Msg("start activate neuron")
 	// block process
-	err := neu.spec.processor.Process(&brainContext{
+	bc := &brainContext{
+		Context:         ctx,
 		b:               b,
 		currentNeuronID: neu.id,
-	})
-	neu.status.state = core.NeuronStateInactive
+		seq:             seq,
+		inputs:          inputs,
+	}
+	err := neu.spec.processor.Process(bc)
+	var cast castDecision
 	if err != nil {
-		neu.status.count.failed++
-		return fmt.Errorf("process neuron error: %w", err)
+		err = fmt.Errorf("process neuron error: %w", err)
+	} else {
+		cast = decideCast(neu, bc)
 	}
 
-	// SucceedCount++
-	neu.status.count.succeed++
-
-	// cast
+	// inactive and cast by maintainer
 	b.publishEvent(maintainEvent{
-		kind:   eventKindNeuron,
-		action: eventActionNeuronTryCast,
-		id:     neu.id,
+		kind:    eventKindNeuron,
+		action:  eventActionNeuronProcessed,
+		id:      neu.id,
+		seq:     seq,
+		err:     err,
+		payload: bc.getOutput(),
+		cast:    cast,
 	})
 
-	return nil
+	return err
 }
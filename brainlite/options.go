@@ -1,7 +1,10 @@
 package brainlite
 
 import (
+	"time"
+
 	"github.com/rs/zerolog"
+	"github.com/zenmodel/zenmodel/core"
 )
 
 // Option configures a BrainLite in build.
@@ -23,13 +26,55 @@ func WithNeuronWorkerNum(workerNun int) Option {
 	})
 }
 
-// WithNeuronQueueLen sets the neuron process queue length
+// WithNeuronWorkerPool sets a dedicated worker pool, neurons labeled with `pool=<pool>` are processed by it.
+// see core.WithWorkerPool
+func WithNeuronWorkerPool(pool string, workerNum int) Option {
+	return optionFunc(func(brain *BrainLite) {
+		brain.poolWorkerNum[pool] = workerNum
+	})
+}
+
+// WithNeuronPriorityAging sets the interval that the priority of waiting neuron increases by 1,
+// aging <= 0 disables aging, neurons with low priority may starve.
+func WithNeuronPriorityAging(aging time.Duration) Option {
+	return optionFunc(func(brain *BrainLite) {
+		brain.priorityAging = aging
+	})
+}
+
+// WithMaxSteps sets the max activations of all neurons in a run, it stops runaway cycles.
+// When it is hit, the neuron casts to its limit cast group or the run ends with *core.LimitError,
+// see core.WithLimitCastGroup
+func WithMaxSteps(maxSteps int) Option {
+	return optionFunc(func(brain *BrainLite) {
+		brain.maxSteps = maxSteps
+	})
+}
+
+// WithNeuronQueueLen sets the initial capacity of neuron process queue, the queue is unbounded
 func WithNeuronQueueLen(nQueueLen int) Option {
 	return optionFunc(func(brain *BrainLite) {
 		brain.nQueueLen = nQueueLen
 	})
 }
 
+// WithReducer sets reducer of memory key, it overrides the reducer registered on blueprint, see core.Reducer
+func WithReducer(key string, reducer core.Reducer) Option {
+	return optionFunc(func(brain *BrainLite) {
+		brain.reducers[key] = reducer
+	})
+}
+
+// WithMemoryWatchInterval sets the interval of polling memory changelog for WatchMemory, changes written by
+// other processes, e.g. Python processors, are sent within the interval
+func WithMemoryWatchInterval(interval time.Duration) Option {
+	return optionFunc(func(brain *BrainLite) {
+		if interval > 0 {
+			brain.changelog.interval = interval
+		}
+	})
+}
+
 // WithLoggerLevel sets the default logger with specific level
 func WithLoggerLevel(level zerolog.Level) Option {
 	return optionFunc(func(brain *BrainLite) {
@@ -1,28 +1,162 @@
 package brainlocal
 
+import (
+	"context"
+	"sync"
+
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/memory"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
 type brainContext struct {
+	// Context of current process, it is canceled when process should stop
+	context.Context
 	b               *BrainLocal
 	currentNeuronID string
+	// seq is the activation sequence of current process
+	seq int
+	// scope is the scoped memory of map item, nil if current process is not an item of map neuron
+	scope *mapScope
+	// inputs is the payloads of in-links which triggered current process
+	inputs []linkInput
+
+	mu     sync.Mutex
+	output interface{}
 }
 
 func (c *brainContext) SetMemory(keysAndValues ...interface{}) error {
-	return c.b.SetMemory(keysAndValues...)
+	return c.updateMemory(func(tx *memoryTx) error {
+		return tx.SetMemory(keysAndValues...)
+	})
 }
 
 func (c *brainContext) GetMemory(key interface{}) interface{} {
+	if c.scope != nil {
+		if v, ok := c.scope.get(key); ok {
+			return v
+		}
+	}
+
 	return c.b.GetMemory(key)
 }
 
 func (c *brainContext) ExistMemory(key interface{}) bool {
+	if c.scope != nil {
+		if _, ok := c.scope.get(key); ok {
+			return true
+		}
+	}
+
 	return c.b.ExistMemory(key)
 }
 
+func (c *brainContext) DecodeMemory(key interface{}, target interface{}) (bool, error) {
+	if c.scope != nil {
+		if v, ok := c.scope.get(key); ok {
+			return true, memory.Convert(v, target)
+		}
+	}
+
+	return c.b.DecodeMemory(key, target)
+}
+
 func (c *brainContext) DeleteMemory(key interface{}) {
-	c.b.DeleteMemory(key)
+	if c.scope != nil {
+		c.scope.del(key)
+		return
+	}
+
+	c.b.deleteMemory(c.currentNeuronID, key)
 }
 
 func (c *brainContext) ClearMemory() {
-	c.b.ClearMemory()
+	if c.scope != nil {
+		c.scope.clear()
+		return
+	}
+
+	c.b.clearMemory(c.currentNeuronID)
+}
+
+func (c *brainContext) ListMemoryKeys() ([]interface{}, error) {
+	var keys []interface{}
+	err := c.RangeMemory(func(key, _ interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	return keys, err
+}
+
+// RangeMemory ranges over the scoped memory of map item first, then the brain memory which is not shadowed by it
+func (c *brainContext) RangeMemory(fn func(key, value interface{}) bool) error {
+	if c.scope == nil {
+		return c.b.RangeMemory(fn)
+	}
+
+	scoped := c.scope.list()
+	shadowed := make(map[any]struct{}, len(scoped))
+	for _, m := range scoped {
+		if k, err := indexMemoryKey(m.key); err == nil {
+			shadowed[k] = struct{}{}
+		}
+		if !fn(m.key, m.value) {
+			return nil
+		}
+	}
+
+	return c.b.RangeMemory(func(key, value any) bool {
+		if k, err := indexMemoryKey(key); err == nil {
+			if _, ok := shadowed[k]; ok {
+				return true
+			}
+		}
+		return fn(key, value)
+	})
+}
+
+func (c *brainContext) UpdateMemory(fn func(tx processor.MemoryTx) error) error {
+	return c.updateMemory(func(tx *memoryTx) error {
+		return fn(tx)
+	})
+}
+
+func (c *brainContext) CompareAndSwapMemory(key, old, new interface{}) (bool, error) {
+	var swapped bool
+	err := c.updateMemory(func(tx *memoryTx) error {
+		var err error
+		swapped, err = tx.compareAndSwap(key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// updateMemory runs fn in transaction of brain memory, or of the scoped memory if current process is a map item
+func (c *brainContext) updateMemory(fn func(tx *memoryTx) error) error {
+	if c.scope == nil {
+		return c.b.updateMemory(c.currentNeuronID, fn)
+	}
+
+	tx := newMemoryTx(func(key any) (any, bool) {
+		if v, ok := c.scope.get(key); ok {
+			return v, true
+		}
+		return c.b.lookupMemory(key)
+	}, nil)
+	if err := tx.run(fn); err != nil {
+		return err
+	}
+	for _, w := range tx.writes {
+		if w.del {
+			c.scope.del(w.key)
+		} else {
+			c.scope.set(w.key, w.value)
+		}
+	}
+
+	return nil
 }
 
 func (c *brainContext) GetCurrentNeuronID() string {
@@ -42,14 +176,55 @@ func (c *brainContext) GetBrainLabels() map[string]string {
 }
 
 func (c *brainContext) ContinueCast() {
-	_, ok := c.b.neurons[c.currentNeuronID]
+	n, ok := c.b.neurons[c.currentNeuronID]
 	if !ok {
 		return
 	}
 
 	c.b.publishEvent(maintainEvent{
-		kind:   eventKindNeuron,
-		action: eventActionNeuronCastAnyway,
-		id:     c.currentNeuronID,
+		kind:    eventKindNeuron,
+		action:  eventActionNeuronCastAnyway,
+		id:      c.currentNeuronID,
+		seq:     c.seq,
+		payload: c.getOutput(),
+		cast:    decideCast(n, c),
 	})
 }
+
+func (c *brainContext) SetOutput(payload interface{}) {
+	if c.scope != nil {
+		// output of map item is its result
+		c.scope.set(core.MapResultMemoryKey, payload)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.output = payload
+}
+
+func (c *brainContext) getOutput() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.output
+}
+
+func (c *brainContext) GetInputs() map[string]interface{} {
+	inputs := make(map[string]interface{}, len(c.inputs))
+	for _, in := range c.inputs {
+		inputs[in.from] = in.payload
+	}
+
+	return inputs
+}
+
+func (c *brainContext) GetInputByLink(linkID string) interface{} {
+	for _, in := range c.inputs {
+		if in.linkID == linkID {
+			return in.payload
+		}
+	}
+
+	return nil
+}
@@ -1,28 +1,35 @@
 package brainlocal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/dgraph-io/ristretto"
 	"github.com/rs/zerolog"
 	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/internal/queue"
 	"github.com/zenmodel/zenmodel/internal/utils"
+	"github.com/zenmodel/zenmodel/memory"
+	"github.com/zenmodel/zenmodel/processor"
 )
 
 const (
-	//  length of brain event queue
+	// initial capacity of brain event queue, the queue is unbounded
 	bQueueLen = 10
-	// default length of neuron process queue
+	// default initial capacity of neuron process queue, the queue is unbounded
 	defaultNQueueLen = 10
 	// default number of neuron process workers
 	defaultNWorkerNum = 4
-	// default number of keys to track frequency of (10M)
+	// default interval that the priority of waiting neuron increases by 1
+	defaultNPriorityAging = time.Second
+	// name of default worker pool, neuron without pool label or with unknown pool is processed by it
+	defaultWorkerPool = ""
+	// default number of keys to track frequency of ristretto store (10M)
 	defaultMemNumCounters = 1e7
-	// default maximum cost of cache (1GB)
+	// default maximum cost of ristretto store (1GB)
 	defaultMemMaxCost = 1 << 30
 )
 
@@ -68,8 +75,11 @@ func BuildBrain(blueprint core.Blueprint, withOpts ...Option) *BrainLocal {
 	}).With().Caller().Timestamp().Logger().Level(zerolog.InfoLevel)
 	b.BrainMaintainer.nQueueLen = defaultNQueueLen
 	b.BrainMaintainer.nWorkerNum = defaultNWorkerNum
+	b.BrainMaintainer.poolWorkerNum = make(map[string]int)
+	b.BrainMaintainer.priorityAging = defaultNPriorityAging
 	b.BrainMemory.numCounters = defaultMemNumCounters
 	b.BrainMemory.maxCost = defaultMemMaxCost
+	b.BrainMemory.reducers = blueprint.ListReducers()
 
 	for _, opt := range withOpts {
 		opt.apply(b)
@@ -90,6 +100,16 @@ type BrainLocal struct {
 
 	// brain is in the Running state when there are 1 or more Activate neuron or 1 or more StandBy link.
 	state core.BrainState
+	// closing is true when brain is shutting down, brain no longer accepts triggers and activates neurons
+	closing bool
+	// runID is the ID of current run, renewed every time brain turns to Running
+	runID string
+	// runErr is the error of current run, e.g. *core.LimitError
+	runErr error
+	// runLimit is the first loop limit of current run which is routed to limit cast group
+	runLimit *core.LimitError
+	// runOutcome is the outcome of the end link which ends current run
+	runOutcome string
 	// brain memories
 	BrainMemory
 	BrainMaintainer
@@ -100,21 +120,52 @@ type BrainLocal struct {
 }
 
 type BrainMemory struct {
-	cache       *ristretto.Cache
+	// mu guards the store pointer, store itself is safe for concurrent use
+	mu    sync.RWMutex
+	store memoryStore
+	// useCache stores memories in ristretto cache instead of the default non-evicting store, see WithCacheMemory
+	useCache    bool
 	numCounters int64
 	maxCost     int64
+	// quota and sizer of the default store, see WithMemoryQuota and WithMemorySizer
+	maxKeys  int
+	maxBytes int64
+	sizer    MemorySizer
+	// reducers of memory key, they are not changed after build
+	reducers map[string]core.Reducer
+	// txMu serializes writes of memory, reads hold its read lock so that half-applied writes are not visible
+	txMu sync.RWMutex
+	// watchers receive changes of memory
+	watchers memoryWatchers
 }
 type BrainMaintainer struct {
-	bQueue chan maintainEvent
+	bQueue *queue.Queue[maintainEvent]
 	stop   chan struct{}
+	// ctx is the root context of all neuron processes, cancel cancels it
+	ctx    context.Context
+	cancel context.CancelFunc
+	// statusMu is held by maintainer while handling event, so that status of neurons and links can be read safely
+	statusMu sync.RWMutex
+	// maxSteps is the max activations of all neurons in a run, 0 means unlimited
+	maxSteps int
+	// steps is the activations of run stepsRunID, changed by maintainer only
+	steps      int
+	stepsRunID string
 
 	NeuronRunner
 }
 
 type NeuronRunner struct {
-	nQueue     chan string
+	// neuron process queue of each worker pool, key is pool name
+	nQueues    map[string]*queue.PriorityQueue[activation]
 	nQueueLen  int
 	nWorkerNum int
+	// worker number of dedicated worker pools, key is pool name
+	poolWorkerNum map[string]int
+	// interval that the priority of waiting neuron increases by 1, avoid starvation of low priority neurons
+	priorityAging time.Duration
+	// inflight is the number of queued and running activations, guarded by BrainLocal.mu
+	inflight int
 }
 
 func (b *BrainLocal) TrigLinks(links ...core.Link) error {
@@ -152,58 +203,201 @@ func (b *BrainLocal) SetMemory(keysAndValues ...interface{}) error {
 	if len(keysAndValues)%2 != 0 {
 		return fmt.Errorf("key and value are not paired")
 	}
+
+	return b.updateMemory("", func(tx *memoryTx) error {
+		return tx.SetMemory(keysAndValues...)
+	})
+}
+
+func (b *BrainLocal) GetMemory(key any) any {
+	v, _ := b.lookupMemory(key)
+
+	return v
+}
+
+func (b *BrainLocal) ExistMemory(key any) bool {
+	_, ok := b.lookupMemory(key)
+
+	return ok
+}
+
+// DecodeMemory converts memory into target, it implements memory.Decoder
+func (b *BrainLocal) DecodeMemory(key any, target any) (bool, error) {
+	v, ok := b.lookupMemory(key)
+	if !ok {
+		return false, nil
+	}
+
+	return true, memory.Convert(v, target)
+}
+
+func (b *BrainLocal) DeleteMemory(key any) {
+	b.deleteMemory("", key)
+}
+
+func (b *BrainLocal) ClearMemory() {
+	b.clearMemory("")
+}
+
+// ListMemoryKeys lists keys of all memories in no particular order, see processor.BrainContext
+func (b *BrainLocal) ListMemoryKeys() ([]any, error) {
+	memories := b.listMemory()
+	keys := make([]any, 0, len(memories))
+	for _, m := range memories {
+		keys = append(keys, m.key)
+	}
+
+	return keys, nil
+}
+
+// RangeMemory calls fn for each memory until fn returns false, see processor.BrainContext
+func (b *BrainLocal) RangeMemory(fn func(key, value any) bool) error {
+	for _, m := range b.listMemory() {
+		if !fn(m.key, m.value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// UpdateMemory runs fn in a memory transaction, see processor.BrainContext
+func (b *BrainLocal) UpdateMemory(fn func(tx processor.MemoryTx) error) error {
+	return b.updateMemory("", func(tx *memoryTx) error {
+		return fn(tx)
+	})
+}
+
+// CompareAndSwapMemory sets memory of key to new if its current value equals old, see processor.BrainContext
+func (b *BrainLocal) CompareAndSwapMemory(key, old, new any) (bool, error) {
+	var swapped bool
+	err := b.updateMemory("", func(tx *memoryTx) error {
+		var err error
+		swapped, err = tx.compareAndSwap(key, old, new)
+		return err
+	})
+
+	return swapped, err
+}
+
+// updateMemory runs fn with write lock of memory held, and applies the writes of tx if fn returns nil.
+// neuronID is the writer sent to watchers
+func (b *BrainLocal) updateMemory(neuronID string, fn func(tx *memoryTx) error) error {
 	if err := b.ensureMemoryInit(); err != nil {
 		// TODO wrap error
 		return err
 	}
+	store := b.getStore()
+	b.BrainMemory.txMu.Lock()
+	defer b.BrainMemory.txMu.Unlock()
 
-	for i := 0; i < len(keysAndValues); i += 2 {
-		k := keysAndValues[i]
-		v := keysAndValues[i+1]
-		b.BrainMemory.cache.Set(k, v, 1) // TODO maybe calculate cost
-		b.logger.Debug().
-			Any("key", k).
-			Any("value", v).
-			Msg("set memory")
+	tx := newMemoryTx(store.Get, b.BrainMemory.reducers)
+	if err := tx.run(fn); err != nil {
+		return err
+	}
+
+	return b.applyMemory(store, neuronID, tx.writes)
+}
+
+// applyMemory should be called with b.BrainMemory.txMu locked, changes are sent to watchers after writes applied
+func (b *BrainLocal) applyMemory(store memoryStore, neuronID string, writes []memoryWrite) error {
+	var changes []core.MemoryChange
+	for _, w := range writes {
+		if !b.BrainMemory.watchers.watching(w.key) {
+			continue
+		}
+		old, existed := store.Get(w.key)
+		if w.del && !existed {
+			continue
+		}
+		changes = append(changes, core.MemoryChange{Key: w.key, Old: old, New: w.value, Deleted: w.del, NeuronID: neuronID})
+	}
+	if err := store.Apply(writes); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		b.BrainMemory.watchers.publish(change)
+	}
+	for _, w := range writes {
+		if w.del {
+			b.logger.Debug().Any("key", w.key).Msg("delete memory")
+		} else {
+			b.logger.Debug().Any("key", w.key).Any("value", w.value).Msg("set memory")
+		}
 	}
-	b.BrainMemory.cache.Wait()
 
 	return nil
 }
 
-func (b *BrainLocal) GetMemory(key any) any {
-	if b.BrainMemory.cache == nil {
-		return nil
+func (b *BrainLocal) deleteMemory(neuronID string, key any) {
+	store := b.getStore()
+	if store == nil {
+		return
 	}
-	v, _ := b.BrainMemory.cache.Get(key)
+	b.BrainMemory.txMu.Lock()
+	defer b.BrainMemory.txMu.Unlock()
 
-	return v
+	if err := b.applyMemory(store, neuronID, []memoryWrite{{key: key, del: true}}); err != nil {
+		b.logger.Error().Err(err).Any("key", key).Msg("delete memory failed")
+	}
 }
 
-func (b *BrainLocal) ExistMemory(key any) bool {
-	if b.BrainMemory.cache == nil {
-		return false
+func (b *BrainLocal) clearMemory(neuronID string) {
+	store := b.getStore()
+	if store == nil {
+		return
 	}
+	b.BrainMemory.txMu.Lock()
+	defer b.BrainMemory.txMu.Unlock()
 
-	_, ok := b.BrainMemory.cache.Get(key)
+	cleared, ok := store.Clear()
+	if !ok {
+		// ristretto can not list keys, so one change without key is sent to all watchers
+		b.BrainMemory.watchers.publish(core.MemoryChange{Deleted: true, NeuronID: neuronID})
+		return
+	}
+	for _, w := range cleared {
+		b.BrainMemory.watchers.publish(core.MemoryChange{Key: w.key, Old: w.value, Deleted: true, NeuronID: neuronID})
+	}
+}
 
-	return ok
+// WatchMemory watches changes of memory keys, or all memories if no key is given, see core.Brain
+func (b *BrainLocal) WatchMemory(ctx context.Context, keys ...any) (<-chan core.MemoryChange, error) {
+	w, err := b.BrainMemory.watchers.add(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("watch memory: %w", err)
+	}
+
+	return w.ch, nil
 }
 
-func (b *BrainLocal) DeleteMemory(key any) {
-	if b.BrainMemory.cache == nil {
-		return
+// lookupMemory reads memory with read lock of memory held, so that half-applied writes are not visible
+func (b *BrainLocal) lookupMemory(key any) (any, bool) {
+	store := b.getStore()
+	if store == nil {
+		return nil, false
 	}
+	b.BrainMemory.txMu.RLock()
+	defer b.BrainMemory.txMu.RUnlock()
 
-	b.BrainMemory.cache.Del(key)
+	return store.Get(key)
 }
 
-func (b *BrainLocal) ClearMemory() {
-	if b.BrainMemory.cache == nil {
-		return
+// snapshotMemory returns all memories for ExportMemory
+func (b *BrainLocal) snapshotMemory() ([]memoryWrite, error) {
+	return b.listMemory(), nil
+}
+
+// listMemory returns a snapshot of memories with read lock of memory held
+func (b *BrainLocal) listMemory() []memoryWrite {
+	store := b.getStore()
+	if store == nil {
+		return nil
 	}
+	b.BrainMemory.txMu.RLock()
+	defer b.BrainMemory.txMu.RUnlock()
 
-	b.BrainMemory.cache.Clear()
+	return store.List()
 }
 
 func (b *BrainLocal) GetState() core.BrainState {
@@ -219,12 +413,59 @@ func (b *BrainLocal) Wait() {
 	b.mu.Unlock()
 }
 
-func (b *BrainLocal) Shutdown() {
+func (b *BrainLocal) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.state == core.BrainStateShutdown { // maintainer not started, release memory only
+		b.mu.Unlock()
+		b.closeMemory()
+		return nil
+	}
+	if b.closing { // shutting down by other goroutine
+		b.mu.Unlock()
+		return b.waitUntil(ctx, func() bool {
+			return b.state == core.BrainStateShutdown
+		})
+	}
+	b.closing = true
+	b.mu.Unlock()
 	b.logger.Info().Msg("brain local shutdown")
-	close(b.BrainMaintainer.nQueue)
-	close(b.BrainMaintainer.bQueue)
-	b.BrainMemory.cache.Close()
-	b.setState(core.BrainStateShutdown)
+
+	// drain active neurons, cancel them if ctx done
+	err := b.waitUntil(ctx, func() bool {
+		return b.inflight == 0
+	})
+	b.mu.Lock()
+	b.closeQueues()
+	b.mu.Unlock()
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("brain shutdown before active neurons done, cancel them")
+		// processors and memory are still used by canceled neurons until they return
+		_ = b.waitUntil(context.Background(), func() bool {
+			return b.inflight == 0
+		})
+	}
+
+	for _, neu := range b.neurons {
+		closer, ok := neu.spec.processor.(processor.Closer)
+		if !ok {
+			continue
+		}
+		if closeErr := closer.Close(); closeErr != nil {
+			b.logger.Error().Err(closeErr).Str("neuronID", neu.id).Msg("close processor failed")
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	b.closeMemory()
+
+	b.mu.Lock()
+	b.closing = false
+	b.state = core.BrainStateShutdown
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	return err
 }
 
 func (b *BrainLocal) trigLinks(linkIDs ...string) error {
@@ -232,69 +473,95 @@ func (b *BrainLocal) trigLinks(linkIDs ...string) error {
 		return nil
 	}
 
+	// ensure brain maintainer start
+	if err := b.ensureMaintainerStart(); err != nil {
+		return err
+	}
+
 	if err := b.ensureMemoryInit(); err != nil {
 		// TODO wrap error
 		return err
 	}
 
-	// ensure brain maintainer start
-	b.ensureMaintainerStart()
-
-	// goroutine wait
-	var wg sync.WaitGroup
+	events := make([]maintainEvent, 0, len(linkIDs))
 	for _, linkID := range linkIDs {
-		l, ok := b.links[linkID]
-		if !ok {
+		if _, ok := b.links[linkID]; !ok {
 			continue
 		}
-
-		wg.Add(1)
-		go b.trigLink(&wg, l)
-	}
-	wg.Wait()
-
-	b.refreshState()
-
-	return nil
-}
-
-func (b *BrainLocal) trigLink(wg *sync.WaitGroup, l *link) {
-	defer wg.Done()
-
-	if l.status.state != core.LinkStateReady {
-		// change link state as ready
-		l.status.state = core.LinkStateReady
-
-		// send maintain event
-		b.publishEvent(maintainEvent{
+		events = append(events, maintainEvent{
 			kind:   eventKindLink,
-			action: eventActionLinkReady,
-			id:     l.id,
+			action: eventActionLinkTrig,
+			id:     linkID,
 		})
 	}
+	// link state is changed by maintainer, wait them handled so that brain is running after trig
+	b.publishEventAndWait(events...)
 
-	return
+	return nil
 }
 
 func (b *BrainLocal) ensureMemoryInit() error {
-	if b.BrainMemory.cache != nil {
+	b.BrainMemory.mu.Lock()
+	defer b.BrainMemory.mu.Unlock()
+	if b.BrainMemory.store != nil {
 		return nil
 	}
 
 	return b.initMemory()
 }
 
+// initMemory should be called with b.BrainMemory.mu locked
 func (b *BrainLocal) initMemory() error {
-	cache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: b.BrainMemory.numCounters,
-		MaxCost:     b.BrainMemory.maxCost,
-		BufferItems: 64, // number of keys per Get buffer.
-	})
+	if !b.BrainMemory.useCache {
+		b.BrainMemory.store = newMapStore(b.BrainMemory.maxKeys, b.BrainMemory.maxBytes, b.BrainMemory.sizer)
+		return nil
+	}
+
+	store, err := newRistrettoStore(b.BrainMemory.numCounters, b.BrainMemory.maxCost)
 	if err != nil {
 		// TODO Wrap error
 		return err
 	}
-	b.BrainMemory.cache = cache
+	b.BrainMemory.store = store
 
 	return nil
 }
+
+func (b *BrainLocal) closeMemory() {
+	b.BrainMemory.watchers.closeAll()
+	b.BrainMemory.mu.Lock()
+	defer b.BrainMemory.mu.Unlock()
+	if b.BrainMemory.store == nil {
+		return
+	}
+
+	b.BrainMemory.store.Close()
+	b.BrainMemory.store = nil
+}
+
+func (b *BrainLocal) getStore() memoryStore {
+	b.BrainMemory.mu.RLock()
+	defer b.BrainMemory.mu.RUnlock()
+
+	return b.BrainMemory.store
+}
+
+// memoryKeys returns the number of memories
+func (b *BrainLocal) memoryKeys() int {
+	store := b.getStore()
+	if store == nil {
+		return 0
+	}
+
+	return store.Len()
+}
+
+// memoryBytes returns the total size of memories, it is 0 if size is not accounted
+func (b *BrainLocal) memoryBytes() int64 {
+	store := b.getStore()
+	if store == nil {
+		return 0
+	}
+
+	return store.Size()
+}
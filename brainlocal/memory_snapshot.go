@@ -0,0 +1,62 @@
+package brainlocal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zenmodel/zenmodel/memory"
+)
+
+// ExportMemory writes memories whose key is selected by filter to w as memory.Snapshot, see core.Brain
+func (b *BrainLocal) ExportMemory(w io.Writer, filter func(key any) bool) error {
+	memories, err := b.snapshotMemory()
+	if err != nil {
+		return fmt.Errorf("export memory: %w", err)
+	}
+	var entries []memory.SnapshotEntry
+	for _, m := range memories {
+		if filter != nil && !filter(m.key) {
+			continue
+		}
+		e, err := memory.NewSnapshotEntry(m.key, m.value)
+		if err != nil {
+			return fmt.Errorf("export memory: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return memory.WriteSnapshot(w, entries)
+}
+
+// ImportMemory sets memories of memory.Snapshot read from r whose key is selected by filter, see core.Brain.
+// They are set in one transaction without reducers
+func (b *BrainLocal) ImportMemory(r io.Reader, filter func(key any) bool) error {
+	entries, err := memory.ReadSnapshot(r)
+	if err != nil {
+		return fmt.Errorf("import memory: %w", err)
+	}
+	var writes []memoryWrite
+	for _, e := range entries {
+		key, value, err := e.Decode()
+		if err != nil {
+			return fmt.Errorf("import memory: %w", err)
+		}
+		if filter != nil && !filter(key) {
+			continue
+		}
+		if _, err = indexMemoryKey(key); err != nil {
+			return fmt.Errorf("import memory: key %s of %s: %w, register its type by memory.RegisterType",
+				e.Key, e.KeyType, err)
+		}
+		writes = append(writes, memoryWrite{key: key, value: value})
+	}
+
+	return b.updateMemory("", func(tx *memoryTx) error {
+		for _, w := range writes {
+			if err := tx.put(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
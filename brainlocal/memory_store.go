@@ -0,0 +1,306 @@
+package brainlocal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/zenmodel/zenmodel/core"
+)
+
+// memoryStore stores memories of BrainLocal, writes are applied by updateMemory with txMu locked
+type memoryStore interface {
+	Get(key any) (any, bool)
+	// Apply applies writes whose keys are unique, it applies none of them if it returns error
+	Apply(writes []memoryWrite) error
+	// Clear deletes all memories, it returns the deleted memories, ok is false if store can not list its memories
+	Clear() (cleared []memoryWrite, ok bool)
+	// List returns a snapshot of memories with their original keys
+	List() []memoryWrite
+	// Len returns the number of memories
+	Len() int
+	// Size returns the total size of memories, it is 0 if size is not accounted
+	Size() int64
+	Close()
+}
+
+// MemorySizer returns the size of memory value, it is used for size accounting and quota, see WithMemorySizer
+type MemorySizer func(value any) (int64, error)
+
+// JSONMemorySizer sizes memory value by the length of its JSON encoding, it is the default MemorySizer
+func JSONMemorySizer(value any) (int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(data)), nil
+}
+
+// mapStore is the default memory store, it never drops memories. Writes which exceed maxKeys or maxBytes fail
+// with core.ErrMemoryQuotaExceeded, size of memory is accounted if sizer is set.
+type mapStore struct {
+	mu       sync.RWMutex
+	memories map[any]mapEntry
+	size     int64
+	maxKeys  int
+	maxBytes int64
+	sizer    MemorySizer
+}
+
+type mapEntry struct {
+	key   any
+	value any
+	size  int64
+}
+
+func newMapStore(maxKeys int, maxBytes int64, sizer MemorySizer) *mapStore {
+	if sizer == nil && maxBytes > 0 {
+		sizer = JSONMemorySizer
+	}
+
+	return &mapStore{
+		memories: make(map[any]mapEntry),
+		maxKeys:  maxKeys,
+		maxBytes: maxBytes,
+		sizer:    sizer,
+	}
+}
+
+func (s *mapStore) Get(key any) (any, bool) {
+	k, err := indexMemoryKey(key)
+	if err != nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.memories[k]
+
+	return e.value, ok
+}
+
+func (s *mapStore) Apply(writes []memoryWrite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// check quota with the result of all writes, so that writes are applied all or none
+	keys := make([]any, len(writes))
+	entries := make([]mapEntry, len(writes))
+	n, size := len(s.memories), s.size
+	for i, w := range writes {
+		k, err := indexMemoryKey(w.key)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+		old, existed := s.memories[k]
+		if existed {
+			n--
+			size -= old.size
+		}
+		if w.del {
+			continue
+		}
+
+		entries[i] = mapEntry{key: k, value: w.value}
+		if s.sizer != nil {
+			if entries[i].size, err = s.sizer(w.value); err != nil {
+				return fmt.Errorf("size memory %v: %w", w.key, err)
+			}
+		}
+		n++
+		size += entries[i].size
+	}
+	if s.maxKeys > 0 && n > s.maxKeys {
+		return fmt.Errorf("%w: %d keys exceed max keys %d", core.ErrMemoryQuotaExceeded, n, s.maxKeys)
+	}
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return fmt.Errorf("%w: %d bytes exceed max bytes %d", core.ErrMemoryQuotaExceeded, size, s.maxBytes)
+	}
+
+	for i, w := range writes {
+		if w.del {
+			delete(s.memories, keys[i])
+		} else {
+			s.memories[keys[i]] = entries[i]
+		}
+	}
+	s.size = size
+
+	return nil
+}
+
+func (s *mapStore) Clear() ([]memoryWrite, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cleared := make([]memoryWrite, 0, len(s.memories))
+	for _, e := range s.memories {
+		cleared = append(cleared, memoryWrite{key: e.key, value: e.value, del: true})
+	}
+	s.memories = make(map[any]mapEntry)
+	s.size = 0
+
+	return cleared, true
+}
+
+func (s *mapStore) List() []memoryWrite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	memories := make([]memoryWrite, 0, len(s.memories))
+	for _, e := range s.memories {
+		memories = append(memories, memoryWrite{key: e.key, value: e.value})
+	}
+
+	return memories
+}
+
+func (s *mapStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.memories)
+}
+
+func (s *mapStore) Size() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.size
+}
+
+func (s *mapStore) Close() {}
+
+// indexMemoryKey makes the key of memory in maps, []byte is the same key as string, and key must be comparable.
+// Stores, transactions, watchers and scoped memories of map item are all keyed by it
+func indexMemoryKey(key any) (any, error) {
+	k := scopeKey(key)
+	if k == nil || !reflect.TypeOf(k).Comparable() {
+		return nil, fmt.Errorf("unsupported memory key type %T", key)
+	}
+
+	return k, nil
+}
+
+// ristrettoStore stores memories in ristretto cache, see WithCacheMemory.
+// Memories may be rejected or evicted when cache is full, and quota is not supported.
+type ristrettoStore struct {
+	cache *ristretto.Cache
+	// keys maps hashed keys in cache to original keys, ristretto can not count or list keys by itself
+	keys   map[uint64]any
+	keysMu sync.Mutex
+}
+
+func newRistrettoStore(numCounters, maxCost int64) (*ristrettoStore, error) {
+	s := &ristrettoStore{keys: make(map[uint64]any)}
+	cache, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: numCounters,
+		MaxCost:     maxCost,
+		BufferItems: 64, // number of keys per Get buffer.
+		OnEvict: func(item *ristretto.Item) {
+			s.delKeyHash(item.Key)
+		},
+		OnReject: func(item *ristretto.Item) {
+			s.delKeyHash(item.Key)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.cache = cache
+
+	return s, nil
+}
+
+func (s *ristrettoStore) Get(key any) (any, bool) {
+	if !ristrettoKey(key) {
+		return nil, false
+	}
+
+	return s.cache.Get(key)
+}
+
+func (s *ristrettoStore) Apply(writes []memoryWrite) error {
+	for _, w := range writes {
+		if !ristrettoKey(w.key) {
+			return fmt.Errorf("unsupported memory key type %T of ristretto cache", w.key)
+		}
+	}
+	for _, w := range writes {
+		keyHash, _ := z.KeyToHash(w.key)
+		if w.del {
+			s.cache.Del(w.key)
+			s.delKeyHash(keyHash)
+			continue
+		}
+		if s.cache.Set(w.key, w.value, 1) { // TODO maybe calculate cost
+			s.keysMu.Lock()
+			s.keys[keyHash] = scopeKey(w.key)
+			s.keysMu.Unlock()
+		}
+	}
+	// wait the writes to be visible
+	s.cache.Wait()
+
+	return nil
+}
+
+func (s *ristrettoStore) Clear() ([]memoryWrite, bool) {
+	s.cache.Clear()
+	s.keysMu.Lock()
+	s.keys = make(map[uint64]any)
+	s.keysMu.Unlock()
+
+	return nil, false
+}
+
+func (s *ristrettoStore) List() []memoryWrite {
+	s.keysMu.Lock()
+	keys := make([]any, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	s.keysMu.Unlock()
+
+	memories := make([]memoryWrite, 0, len(keys))
+	for _, k := range keys {
+		if v, ok := s.cache.Get(k); ok {
+			memories = append(memories, memoryWrite{key: k, value: v})
+		}
+	}
+
+	return memories
+}
+
+func (s *ristrettoStore) Len() int {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	return len(s.keys)
+}
+
+func (s *ristrettoStore) Size() int64 {
+	return 0
+}
+
+func (s *ristrettoStore) Close() {
+	s.cache.Close()
+}
+
+// ristrettoKey reports whether key is supported by ristretto, see z.KeyToHash
+func ristrettoKey(key any) bool {
+	switch key.(type) {
+	case uint64, string, []byte, byte, int, int32, uint32, int64:
+		return true
+	}
+
+	return false
+}
+
+func (s *ristrettoStore) delKeyHash(keyHash uint64) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	delete(s.keys, keyHash)
+}
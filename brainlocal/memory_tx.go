@@ -0,0 +1,148 @@
+package brainlocal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/zenmodel/zenmodel/core"
+)
+
+// memoryWrite is one buffered write of memoryTx, value is ignored if del is true
+type memoryWrite struct {
+	key   any
+	value any
+	del   bool
+}
+
+// memoryTx implements processor.MemoryTx, it buffers writes until commit, reads see the buffered writes first
+type memoryTx struct {
+	// lookup reads the committed memory
+	lookup   func(key any) (any, bool)
+	reducers map[string]core.Reducer
+	writes   []memoryWrite
+	// index is the position of key in writes, see indexMemoryKey
+	index map[any]int
+	// err is the first error of DeleteMemory, tx fails with it
+	err error
+}
+
+func newMemoryTx(lookup func(key any) (any, bool), reducers map[string]core.Reducer) *memoryTx {
+	return &memoryTx{
+		lookup:   lookup,
+		reducers: reducers,
+		index:    make(map[any]int),
+	}
+}
+
+func (tx *memoryTx) GetMemory(key interface{}) interface{} {
+	v, _ := tx.get(key)
+	return v
+}
+
+func (tx *memoryTx) ExistMemory(key interface{}) bool {
+	_, ok := tx.get(key)
+	return ok
+}
+
+func (tx *memoryTx) SetMemory(keysAndValues ...interface{}) error {
+	if len(keysAndValues)%2 != 0 {
+		return fmt.Errorf("key and value are not paired")
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		k := keysAndValues[i]
+		v := keysAndValues[i+1]
+		if key, ok := k.(string); ok && tx.reducers[key] != nil {
+			old, _ := tx.get(k)
+			reduced, err := tx.reducers[key](old, v)
+			if err != nil {
+				return fmt.Errorf("reduce memory %v: %w", k, err)
+			}
+			v = reduced
+		}
+		if err := tx.put(memoryWrite{key: k, value: v}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *memoryTx) DeleteMemory(key interface{}) {
+	if err := tx.put(memoryWrite{key: key, del: true}); err != nil && tx.err == nil {
+		tx.err = err
+	}
+}
+
+func (tx *memoryTx) get(key any) (any, bool) {
+	k, err := indexMemoryKey(key)
+	if err != nil {
+		return nil, false
+	}
+	if i, ok := tx.index[k]; ok {
+		w := tx.writes[i]
+		return w.value, !w.del
+	}
+
+	return tx.lookup(key)
+}
+
+// put buffers write, the last write of the same key wins
+func (tx *memoryTx) put(w memoryWrite) error {
+	k, err := indexMemoryKey(w.key)
+	if err != nil {
+		return err
+	}
+	if i, ok := tx.index[k]; ok {
+		tx.writes[i] = w
+		return nil
+	}
+	tx.index[k] = len(tx.writes)
+	tx.writes = append(tx.writes, w)
+
+	return nil
+}
+
+// compareAndSwap writes new without reducer if current memory of key equals old
+func (tx *memoryTx) compareAndSwap(key, old, new any) (bool, error) {
+	if _, err := indexMemoryKey(key); err != nil {
+		return false, err
+	}
+	current, _ := tx.get(key)
+	if !memoryEqual(current, old) {
+		return false, nil
+	}
+
+	return true, tx.put(memoryWrite{key: key, value: new})
+}
+
+// run runs fn in tx, it returns the error of fn or the first error of DeleteMemory
+func (tx *memoryTx) run(fn func(tx *memoryTx) error) error {
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.err
+}
+
+// memoryEqual reports whether a and b are deep equal or have the same JSON encoding,
+// so that values decoded from JSON memory equal the values set, e.g. 1 and 1.0, []string and []any.
+func memoryEqual(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(aj, bj)
+}
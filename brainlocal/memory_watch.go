@@ -0,0 +1,145 @@
+package brainlocal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/internal/queue"
+)
+
+// memoryWatchers sends memory changes to watchers, publish never blocks on slow receivers
+type memoryWatchers struct {
+	mu       sync.RWMutex
+	watchers map[*memoryWatcher]struct{}
+}
+
+// memoryWatcher buffers changes in an unbounded queue, and forwards them to its channel in order
+type memoryWatcher struct {
+	// keys is the watched keys made by indexMemoryKey, nil watches all
+	keys     map[any]struct{}
+	queue    *queue.Queue[core.MemoryChange]
+	ch       chan core.MemoryChange
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// add adds watcher of keys, it is removed when ctx is done. It returns error if any key is not supported by memory
+func (ws *memoryWatchers) add(ctx context.Context, keys []any) (*memoryWatcher, error) {
+	w := &memoryWatcher{
+		queue: queue.New[core.MemoryChange](0),
+		ch:    make(chan core.MemoryChange),
+		stop:  make(chan struct{}),
+	}
+	if len(keys) > 0 {
+		w.keys = make(map[any]struct{}, len(keys))
+		for _, k := range keys {
+			mk, err := indexMemoryKey(k)
+			if err != nil {
+				return nil, err
+			}
+			w.keys[mk] = struct{}{}
+		}
+	}
+
+	ws.mu.Lock()
+	if ws.watchers == nil {
+		ws.watchers = make(map[*memoryWatcher]struct{})
+	}
+	ws.watchers[w] = struct{}{}
+	ws.mu.Unlock()
+
+	go w.forward()
+	go func() {
+		select {
+		case <-ctx.Done():
+			ws.remove(w)
+		case <-w.stop:
+		}
+	}()
+
+	return w, nil
+}
+
+func (ws *memoryWatchers) remove(w *memoryWatcher) {
+	ws.mu.Lock()
+	delete(ws.watchers, w)
+	ws.mu.Unlock()
+	w.close()
+}
+
+// closeAll removes all watchers and closes their channels, e.g. brain shutdown
+func (ws *memoryWatchers) closeAll() {
+	ws.mu.Lock()
+	watchers := ws.watchers
+	ws.watchers = nil
+	ws.mu.Unlock()
+	for w := range watchers {
+		w.close()
+	}
+}
+
+func (ws *memoryWatchers) len() int {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	return len(ws.watchers)
+}
+
+// watching reports whether any watcher watches key, so that old value is read only if needed
+func (ws *memoryWatchers) watching(key any) bool {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	for w := range ws.watchers {
+		if w.watch(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ws *memoryWatchers) publish(change core.MemoryChange) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	for w := range ws.watchers {
+		if change.Key == nil || w.watch(change.Key) {
+			w.queue.Push(change)
+		}
+	}
+}
+
+func (w *memoryWatcher) watch(key any) bool {
+	if w.keys == nil {
+		return true
+	}
+	k, err := indexMemoryKey(key)
+	if err != nil {
+		return false
+	}
+	_, ok := w.keys[k]
+
+	return ok
+}
+
+func (w *memoryWatcher) forward() {
+	defer close(w.ch)
+	for {
+		change, ok := w.queue.Pop()
+		if !ok {
+			return
+		}
+		select {
+		case w.ch <- change:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *memoryWatcher) close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.queue.Close()
+	})
+}
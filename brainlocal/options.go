@@ -1,7 +1,10 @@
 package brainlocal
 
 import (
+	"time"
+
 	"github.com/rs/zerolog"
+	"github.com/zenmodel/zenmodel/core"
 )
 
 // Option configures a BrainLocal in build.
@@ -23,18 +26,76 @@ func WithNeuronWorkerNum(workerNun int) Option {
 	})
 }
 
-// WithNeuronQueueLen sets the neuron process queue length
+// WithNeuronWorkerPool sets a dedicated worker pool, neurons labeled with `pool=<pool>` are processed by it.
+// see core.WithWorkerPool
+func WithNeuronWorkerPool(pool string, workerNum int) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.poolWorkerNum[pool] = workerNum
+	})
+}
+
+// WithNeuronPriorityAging sets the interval that the priority of waiting neuron increases by 1,
+// aging <= 0 disables aging, neurons with low priority may starve.
+func WithNeuronPriorityAging(aging time.Duration) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.priorityAging = aging
+	})
+}
+
+// WithMaxSteps sets the max activations of all neurons in a run, it stops runaway cycles.
+// When it is hit, the neuron casts to its limit cast group or the run ends with *core.LimitError,
+// see core.WithLimitCastGroup
+func WithMaxSteps(maxSteps int) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.maxSteps = maxSteps
+	})
+}
+
+// WithNeuronQueueLen sets the initial capacity of neuron process queue, the queue is unbounded
 func WithNeuronQueueLen(nQueueLen int) Option {
 	return optionFunc(func(brain *BrainLocal) {
 		brain.nQueueLen = nQueueLen
 	})
 }
 
-// WithMemorySetting sets the memory setting
+// WithMemorySetting sets the memory setting of ristretto cache.
+//
+// Deprecated: use WithCacheMemory, memories are stored in ristretto cache only if it is set.
 func WithMemorySetting(memoryNumCounters, memoryMaxCost int64) Option {
+	return WithCacheMemory(memoryNumCounters, memoryMaxCost)
+}
+
+// WithCacheMemory stores memories in ristretto cache instead of the default non-evicting store.
+// Cost of each memory is 1, memories may be rejected or evicted when cache is full, and memory quota is not supported.
+func WithCacheMemory(numCounters, maxCost int64) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.useCache = true
+		brain.numCounters = numCounters
+		brain.maxCost = maxCost
+	})
+}
+
+// WithMemoryQuota limits the number of memories and their total size, 0 is unlimited.
+// Writes which exceed quota fail with core.ErrMemoryQuotaExceeded and none of them is applied.
+// Size is accounted by JSONMemorySizer unless WithMemorySizer is set.
+func WithMemoryQuota(maxKeys int, maxBytes int64) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.maxKeys = maxKeys
+		brain.maxBytes = maxBytes
+	})
+}
+
+// WithMemorySizer enables size accounting of memories with sizer, the total size is reported in core.BrainStatus
+func WithMemorySizer(sizer MemorySizer) Option {
+	return optionFunc(func(brain *BrainLocal) {
+		brain.sizer = sizer
+	})
+}
+
+// WithReducer sets reducer of memory key, it overrides the reducer registered on blueprint, see core.Reducer
+func WithReducer(key string, reducer core.Reducer) Option {
 	return optionFunc(func(brain *BrainLocal) {
-		brain.numCounters = memoryNumCounters
-		brain.maxCost = memoryMaxCost
+		brain.reducers[key] = reducer
 	})
 }
 
@@ -0,0 +1,85 @@
+package brainlocal
+
+import (
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/internal/queue"
+)
+
+func (b *BrainLocal) Status() core.BrainStatus {
+	// neurons and links are not changed while status is being read, see maintain
+	b.statusMu.RLock()
+	defer b.statusMu.RUnlock()
+
+	status := core.BrainStatus{
+		ID:          b.id,
+		Neurons:     make(map[string]core.NeuronStatus, len(b.neurons)),
+		Links:       make(map[string]core.LinkStatus, len(b.links)),
+		Pending:     make([]core.PendingActivation, 0),
+		MemoryKeys:  b.memoryKeys(),
+		MemoryBytes: b.memoryBytes(),
+	}
+	for _, neu := range b.neurons {
+		status.Neurons[neu.id] = core.NeuronStatus{
+			State:    neu.status.state,
+			Seq:      neu.status.seq,
+			RunID:    neu.status.runID,
+			Process:  neu.status.count.process,
+			Succeed:  neu.status.count.succeed,
+			Failed:   neu.status.count.failed,
+			Canceled: neu.status.count.canceled,
+		}
+	}
+	for _, l := range b.links {
+		status.Links[l.id] = core.LinkStatus{
+			State:        l.status.state,
+			From:         l.spec.from,
+			To:           l.spec.to,
+			PendingCasts: len(l.status.pendingCasts),
+		}
+	}
+
+	b.mu.Lock()
+	status.State = b.state
+	status.RunID = b.runID
+	nQueues := make(map[string]*queue.PriorityQueue[activation], len(b.nQueues))
+	for pool, nQueue := range b.nQueues {
+		nQueues[pool] = nQueue
+	}
+	b.mu.Unlock()
+
+	for pool, nQueue := range nQueues {
+		for _, act := range nQueue.Items() {
+			var priority int
+			if neu, ok := b.neurons[act.neuronID]; ok {
+				priority = neu.spec.priority
+			}
+			status.Pending = append(status.Pending, core.PendingActivation{
+				NeuronID: act.neuronID,
+				Seq:      act.seq,
+				Pool:     pool,
+				Priority: priority,
+			})
+		}
+	}
+
+	return status
+}
+
+func (b *BrainLocal) GetRunResult() core.RunResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return core.RunResult{
+		RunID:   b.runID,
+		Err:     b.runErr,
+		Outcome: b.runOutcome,
+		Limit:   b.runLimit,
+	}
+}
+
+func (b *BrainLocal) GetOutcome() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.runOutcome
+}
@@ -39,6 +39,8 @@ type brainprint struct {
 	neurons map[string]*neuron
 	// map of all link
 	links map[string]*link
+	// reducers of memory key
+	reducers map[string]core.Reducer
 }
 
 func (b *brainprint) GetID() string {
@@ -282,9 +284,28 @@ func (b *brainprint) Clone() core.Blueprint {
 	for id, l := range b.links {
 		cp.links[id] = l.deepCopy()
 	}
+	if b.reducers != nil {
+		cp.reducers = b.ListReducers()
+	}
 	return cp
 }
 
+func (b *brainprint) AddReducer(key string, reducer core.Reducer) {
+	if b.reducers == nil {
+		b.reducers = make(map[string]core.Reducer)
+	}
+	b.reducers[key] = reducer
+}
+
+// ListReducers returns copy of reducers, key is memory key
+func (b *brainprint) ListReducers() map[string]core.Reducer {
+	reducers := make(map[string]core.Reducer, len(b.reducers))
+	for k, r := range b.reducers {
+		reducers[k] = r
+	}
+	return reducers
+}
+
 func (b *brainprint) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("id", b.id).
 		Any("labels", b.labels).
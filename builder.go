@@ -0,0 +1,220 @@
+package zenmodel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+// Builder builds Blueprint with higher-level constructs: chains, branches, parallel joins and loops.
+// Errors of every step are collected and returned by Build, so that steps can be chained without error checks.
+// A nil Neuron as the destination of a construct links to END neuron.
+type Builder struct {
+	bp   core.Blueprint
+	errs []error
+	// branched and looped are IDs of neurons used by Branch and Loop, Build rejects neuron used by both
+	branched map[string]struct{}
+	looped   map[string]struct{}
+}
+
+// NewBuilder creates Builder on a new Blueprint
+func NewBuilder() *Builder {
+	return NewBuilderFrom(NewBlueprint())
+}
+
+// NewBuilderFrom creates Builder on an existing Blueprint
+func NewBuilderFrom(bp core.Blueprint) *Builder {
+	return &Builder{
+		bp:       bp,
+		branched: make(map[string]struct{}),
+		looped:   make(map[string]struct{}),
+	}
+}
+
+// Neuron adds Neuron to Blueprint
+func (b *Builder) Neuron(processFn func(bc processor.BrainContext) error, withOpts ...core.NeuronOption) core.Neuron {
+	return b.bp.AddNeuron(processFn, withOpts...)
+}
+
+// Entry adds Entry Links to the neurons
+func (b *Builder) Entry(to ...core.Neuron) *Builder {
+	for _, n := range to {
+		b.link(nil, n)
+	}
+	return b
+}
+
+// End adds End Link from the neuron
+func (b *Builder) End(from core.Neuron, withOpts ...core.LinkOption) *Builder {
+	b.link(from, nil, withOpts...)
+	return b
+}
+
+// Link adds Link from one neuron to another
+func (b *Builder) Link(from, to core.Neuron, withOpts ...core.LinkOption) *Builder {
+	b.link(from, to, withOpts...)
+	return b
+}
+
+// Chain links neurons one by one in order, a nil Neuron at the end links to END.
+func (b *Builder) Chain(neurons ...core.Neuron) *Builder {
+	for i := 0; i+1 < len(neurons); i++ {
+		if neurons[i] == nil {
+			b.errorf("chain: neuron %d is nil", i)
+			return b
+		}
+		b.link(neurons[i], neurons[i+1])
+	}
+	return b
+}
+
+// Branch links src to each branch, a branch is a cast group named by its key, and selector selects the branch to
+// cast after src processed. A nil branch links to END, the branch name is the outcome of the end link.
+// The selector is not bound if any branch fails.
+func (b *Builder) Branch(src core.Neuron, selector processor.Selector, branches map[string]core.Neuron) *Builder {
+	if src == nil || selector == nil {
+		b.errorf("branch: source neuron and selector are required")
+		return b
+	}
+	b.branched[src.GetID()] = struct{}{}
+	errs := len(b.errs)
+	names := make([]string, 0, len(branches))
+	for name := range branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var opts []core.LinkOption
+		if branches[name] == nil {
+			opts = append(opts, core.WithOutcome(name))
+		}
+		l := b.link(src, branches[name], opts...)
+		if l == nil {
+			continue
+		}
+		if err := src.AddCastGroup(name, l); err != nil {
+			b.errorf("branch %s: %w", name, err)
+		}
+	}
+	if len(b.errs) == errs {
+		src.BindCastGroupSelector(selector)
+	}
+
+	return b
+}
+
+// Parallel casts src to all workers at the same time, and join is activated after all workers done.
+// A nil src triggers the workers by Entry Links.
+func (b *Builder) Parallel(src, join core.Neuron, workers ...core.Neuron) *Builder {
+	if join == nil || len(workers) == 0 {
+		b.errorf("parallel: join neuron and workers are required")
+		return b
+	}
+	joinLinks := make([]core.Link, 0, len(workers))
+	for _, w := range workers {
+		b.link(src, w)
+		if l := b.link(w, join); l != nil {
+			joinLinks = append(joinLinks, l)
+		}
+	}
+	if len(joinLinks) == len(workers) {
+		if err := join.AddTriggerGroup(joinLinks...); err != nil {
+			b.errorf("parallel: %w", err)
+		}
+	}
+
+	return b
+}
+
+// Loop activates body again until the condition holds, then casts to exit. A nil exit links to END.
+// The loop links are conditional Links, other out-links of body are cast as usual on every round.
+// The loop links are in the default cast group, so body can not be the source of Branch, Build returns error.
+func (b *Builder) Loop(body core.Neuron, until func(bcr processor.BrainContextReader) bool, exit core.Neuron) *Builder {
+	if body == nil || until == nil {
+		b.errorf("loop: body neuron and condition are required")
+		return b
+	}
+	b.looped[body.GetID()] = struct{}{}
+	b.link(body, body, core.WithCondition(func(bcr processor.BrainContextReader) bool {
+		return !until(bcr)
+	}))
+	b.link(body, exit, core.WithCondition(until))
+
+	return b
+}
+
+// Build returns the Blueprint, and the errors of all steps if any
+func (b *Builder) Build() (core.Blueprint, error) {
+	errs := append([]error(nil), b.errs...)
+	ids := make([]string, 0, len(b.looped))
+	for id := range b.looped {
+		if _, ok := b.branched[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		errs = append(errs, fmt.Errorf("neuron %s: loop links are never cast by the selector of branch", id))
+	}
+	if len(errs) > 0 {
+		return b.bp, &BuildError{Errs: errs}
+	}
+
+	return b.bp, nil
+}
+
+// link adds Link, nil from is Entry Link and nil to is End Link. It returns nil if failed.
+func (b *Builder) link(from, to core.Neuron, withOpts ...core.LinkOption) core.Link {
+	var (
+		l   core.Link
+		err error
+	)
+	switch {
+	case from == nil && to == nil:
+		err = fmt.Errorf("link: both neurons are nil")
+	case from == nil:
+		l, err = b.bp.AddEntryLinkTo(to, withOpts...)
+	case to == nil:
+		l, err = b.bp.AddEndLinkFrom(from, withOpts...)
+	default:
+		l, err = b.bp.AddLink(from, to, withOpts...)
+	}
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return nil
+	}
+
+	return l
+}
+
+func (b *Builder) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Errorf(format, args...))
+}
+
+// BuildError is the errors collected by Builder
+type BuildError struct {
+	Errs []error
+}
+
+func (e *BuildError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("build blueprint: %s", strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first collected error, so that errors.Is and errors.As match it.
+// Check Errs for the others.
+func (e *BuildError) Unwrap() error {
+	if len(e.Errs) == 0 {
+		return nil
+	}
+
+	return e.Errs[0]
+}
@@ -29,6 +29,10 @@ type Blueprint interface {
 	AddEntryLinkTo(neuron Neuron, withOpts ...LinkOption) (Link, error)
 	AddEndLinkFrom(neuron Neuron, withOpts ...LinkOption) (Link, error)
 
+	// AddReducer registers reducer of memory key, it is applied when the memory of key is set
+	AddReducer(key string, reducer Reducer)
+	ListReducers() map[string]Reducer
+
 	Clone() Blueprint
 }
 
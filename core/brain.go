@@ -1,5 +1,12 @@
 package core
 
+import (
+	"context"
+	"io"
+
+	"github.com/zenmodel/zenmodel/processor"
+)
+
 const (
 	// BrainStateShutdown brain 实现所使用的资源均已经释放或清空
 	BrainStateShutdown BrainState = "Shutdown"
@@ -30,10 +37,38 @@ type Brain interface {
 	DeleteMemory(key any)
 	// ClearMemory clear all memories
 	ClearMemory()
+	// ListMemoryKeys lists keys of all memories in no particular order, see processor.BrainContext
+	ListMemoryKeys() ([]any, error)
+	// RangeMemory calls fn for each memory until fn returns false, see processor.BrainContext
+	RangeMemory(fn func(key, value any) bool) error
+	// UpdateMemory runs fn in a memory transaction, see processor.BrainContext
+	UpdateMemory(fn func(tx processor.MemoryTx) error) error
+	// CompareAndSwapMemory sets memory of key to new if its current value equals old, see processor.BrainContext
+	CompareAndSwapMemory(key, old, new any) (bool, error)
+	// ExportMemory writes memories whose key is selected by filter to w as memory.Snapshot JSON, nil filter selects all.
+	// Values are encoded as JSON tagged with their types, register types by memory.RegisterType to restore them
+	ExportMemory(w io.Writer, filter func(key any) bool) error
+	// ImportMemory sets memories of memory.Snapshot read from r whose key is selected by filter, nil filter selects all.
+	// They are set in one transaction without reducers, other memories of brain are kept
+	ImportMemory(r io.Reader, filter func(key any) bool) error
+	// WatchMemory watches changes of memory keys, or all memories if no key is given. Changes are sent in the order
+	// they are written, and buffered until received so that writers do not wait for receivers.
+	// The channel is closed when ctx is done or brain shuts down. It returns error if any key is not supported
+	WatchMemory(ctx context.Context, keys ...any) (<-chan MemoryChange, error)
 	// GetState get brain state
 	GetState() BrainState
+	// GetRunResult get the result of current run, or the last run if brain is Sleeping
+	GetRunResult() RunResult
+	// GetOutcome get the outcome of current run, or the last run if brain is Sleeping, see WithOutcome
+	GetOutcome() string
+	// Status get a snapshot of brain, including state and counters of neurons and links, pending activations
+	// and memory key count. It is safe to be called while brain is running.
+	Status() BrainStatus
 	// Wait wait util brain maintainer shutdown, which means brain state is `Sleeping`
 	Wait()
-	// Shutdown the brain
-	Shutdown()
+	// Shutdown the brain gracefully. It stops accepting triggers, waits active neurons done until ctx is done,
+	// then cancels the remaining neurons and waits them return, closes processors which implement processor.Closer
+	// and releases memory. It returns ctx.Err() if active neurons are canceled.
+	// It is safe to be called concurrently with Entry, TrigLinks and ContinueCast.
+	Shutdown(ctx context.Context) error
 }
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/zenmodel/zenmodel/internal/utils"
+)
+
+const (
+	// NeuronLabelCastRetryDelay is the label key of the delay before first retry of ContinueCast, e.g. `500ms`
+	NeuronLabelCastRetryDelay = "cast_retry_delay"
+	// NeuronLabelCastRetryMultiplier is the label key of the growth factor of retry delay, e.g. `2`
+	NeuronLabelCastRetryMultiplier = "cast_retry_multiplier"
+	// NeuronLabelCastRetryMaxAttempts is the label key of max retry attempts, 0 means unlimited
+	NeuronLabelCastRetryMaxAttempts = "cast_retry_max_attempts"
+	// NeuronLabelCastRetryOverflow is the label key of what to do when retry attempts are exhausted, `drop` or `queue`
+	NeuronLabelCastRetryOverflow = "cast_retry_overflow"
+	// NeuronLabelCastMode is the label key of cast mode, `poll` or `stream`
+	NeuronLabelCastMode = "cast_mode"
+)
+
+const (
+	defaultCastRetryDelay      = 500 * time.Millisecond
+	defaultCastRetryMultiplier = 1
+)
+
+// CastOverflow decides what to do with the pending cast when retry attempts are exhausted
+type CastOverflow string
+
+const (
+	// CastOverflowDrop drops the pending cast
+	CastOverflowDrop CastOverflow = "drop"
+	// CastOverflowQueue keeps the pending cast, it is cast when the destination neuron consumed the link
+	CastOverflowQueue CastOverflow = "queue"
+)
+
+// CastMode decides how ContinueCast deals with out-links which are not consumed yet
+type CastMode string
+
+const (
+	// CastModePoll retries the cast by CastRetryPolicy until the out-link is consumed
+	CastModePoll CastMode = "poll"
+	// CastModeStream is built for long-running producers, every ContinueCast is queued on the out-link
+	// which is still Ready or whose destination neuron is still active, and it is cast when the destination
+	// neuron finished, no polling at all
+	CastModeStream CastMode = "stream"
+)
+
+// CastRetryPolicy configures how ContinueCast retries when out-links are not consumed yet, it works in CastModePoll.
+type CastRetryPolicy struct {
+	// Delay before the first retry, default is 500ms
+	Delay time.Duration
+	// Multiplier is the growth factor of delay for each retry, default is 1 which means fixed delay
+	Multiplier float64
+	// MaxAttempts is the max retry attempts, 0 means unlimited
+	MaxAttempts int
+	// Overflow decides what to do when attempts are exhausted, default is CastOverflowDrop
+	Overflow CastOverflow
+}
+
+// DefaultCastRetryPolicy retries every 500ms without limit
+func DefaultCastRetryPolicy() CastRetryPolicy {
+	return CastRetryPolicy{
+		Delay:      defaultCastRetryDelay,
+		Multiplier: defaultCastRetryMultiplier,
+		Overflow:   CastOverflowDrop,
+	}
+}
+
+// Backoff returns the delay of the attempt, attempt starts from 1
+func (p CastRetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	return time.Duration(float64(p.Delay) * math.Pow(multiplier, float64(attempt-1)))
+}
+
+// CastRetryPolicyFromLabels parses CastRetryPolicy from neuron labels, invalid values fall back to default
+func CastRetryPolicyFromLabels(labels map[string]string) CastRetryPolicy {
+	p := DefaultCastRetryPolicy()
+	if d, err := time.ParseDuration(labels[NeuronLabelCastRetryDelay]); err == nil && d > 0 {
+		p.Delay = d
+	}
+	if m, err := strconv.ParseFloat(labels[NeuronLabelCastRetryMultiplier], 64); err == nil && m >= 1 {
+		p.Multiplier = m
+	}
+	if n, err := strconv.Atoi(labels[NeuronLabelCastRetryMaxAttempts]); err == nil && n >= 0 {
+		p.MaxAttempts = n
+	}
+	if o := CastOverflow(labels[NeuronLabelCastRetryOverflow]); o == CastOverflowQueue {
+		p.Overflow = o
+	}
+
+	return p
+}
+
+// CastModeFromLabels parses CastMode from neuron labels, default is CastModePoll
+func CastModeFromLabels(labels map[string]string) CastMode {
+	if CastMode(labels[NeuronLabelCastMode]) == CastModeStream {
+		return CastModeStream
+	}
+
+	return CastModePoll
+}
+
+// WithCastRetryPolicy sets the ContinueCast retry policy for Neuron
+func WithCastRetryPolicy(policy CastRetryPolicy) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		labels := map[string]string{
+			NeuronLabelCastRetryDelay:       policy.Delay.String(),
+			NeuronLabelCastRetryMultiplier:  strconv.FormatFloat(policy.Multiplier, 'f', -1, 64),
+			NeuronLabelCastRetryMaxAttempts: strconv.Itoa(policy.MaxAttempts),
+			NeuronLabelCastRetryOverflow:    string(policy.Overflow),
+		}
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), labels))
+	})
+}
+
+// WithStreamProducer sets Neuron as a streaming producer, see CastModeStream
+func WithStreamProducer() NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), map[string]string{NeuronLabelCastMode: string(CastModeStream)}))
+	})
+}
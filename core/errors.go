@@ -0,0 +1,34 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBrainShuttingDown is returned when brain is shutting down and no longer accepts triggers
+	ErrBrainShuttingDown = errors.New("brain is shutting down")
+	// ErrMaxStepsExceeded is the run error when the activations of a run exceed the brain max steps
+	ErrMaxStepsExceeded = errors.New("max steps exceeded")
+	// ErrMaxActivationsExceeded is the run error when the activations of a neuron in a run exceed its max activations
+	ErrMaxActivationsExceeded = errors.New("max activations exceeded")
+	// ErrMemoryQuotaExceeded is returned when memory writes exceed the memory quota, none of the writes is applied
+	ErrMemoryQuotaExceeded = errors.New("memory quota exceeded")
+)
+
+// LimitError is the run error when a loop limit is hit, it reports the offending neuron.
+// Use errors.Is with ErrMaxStepsExceeded or ErrMaxActivationsExceeded to distinguish the limit.
+type LimitError struct {
+	// NeuronID is the neuron which is about to be activated when the limit is hit
+	NeuronID string
+	Limit    int
+	Err      error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("neuron %s: %v, limit is %d", e.NeuronID, e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
@@ -1,10 +1,22 @@
 package core
 
+import (
+	"github.com/zenmodel/zenmodel/internal/utils"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
 const (
 	EntryLinkFrom = "__EXTERNAL_SIGNAL__"
 	EndLinkTo     = EndNeuronID
 )
 
+const (
+	// LinkLabelOutcome is the label key of end link, the value is the name of outcome when the run ends by the link
+	LinkLabelOutcome = "outcome"
+	// LinkLabelConditionExpr is the label key of link condition expression, see WithConditionExpr
+	LinkLabelConditionExpr = "condition_expr"
+)
+
 type LinkState string
 
 const (
@@ -20,10 +32,16 @@ type Link interface {
 	GetDestNeuronID() string
 	IsEntryLink() bool
 	IsEndLink() bool
+	// GetCondition get the predicate of link, nil if link is not conditional
+	GetCondition() LinkCondition
 
 	SetLabels(labels map[string]string)
+	SetCondition(condition LinkCondition)
 }
 
+// LinkCondition is the predicate of conditional link, it is evaluated when the source neuron casts
+type LinkCondition func(ctx processor.BrainContextReader) bool
+
 // LinkOption configures a link.
 type LinkOption interface {
 	Apply(link Link)
@@ -42,3 +60,47 @@ func WithLinkLabels(labels map[string]string) LinkOption {
 		link.SetLabels(labels)
 	})
 }
+
+// WithOutcome names the outcome of end link, e.g. "answered", "gave_up" or "escalated".
+// When the run ends by the link, brain records the outcome, see Brain.GetOutcome and RunResult.Outcome
+func WithOutcome(name string) LinkOption {
+	return linkOptionFunc(func(link Link) {
+		link.SetLabels(utils.MergeLabels(link.GetLabels(), map[string]string{LinkLabelOutcome: name}))
+	})
+}
+
+// WithCondition makes Link conditional, it is cast only when condition holds. Conditions are evaluated when the
+// source neuron casts (including ContinueCast), for the links of the selected cast group, so that routing needs no
+// named cast groups and selector. All links whose condition holds are cast in parallel; when none holds, only the
+// unconditional links of the cast group are cast, and the source neuron casts nothing if there is none.
+func WithCondition(condition func(ctx processor.BrainContextReader) bool) LinkOption {
+	return linkOptionFunc(func(link Link) {
+		link.SetCondition(condition)
+	})
+}
+
+// WithConditionExpr makes Link conditional by expression evaluated over memory, see processor.NewExprCondition.
+// The expression is recorded in labels, so that it can be declared in blueprint config. It returns error if
+// expression is invalid.
+func WithConditionExpr(expression string) (LinkOption, error) {
+	condition, err := processor.NewExprCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return linkOptionFunc(func(link Link) {
+		link.SetLabels(utils.MergeLabels(link.GetLabels(), map[string]string{LinkLabelConditionExpr: expression}))
+		link.SetCondition(condition)
+	}), nil
+}
+
+// MustConditionExpr is like WithConditionExpr but panics if expression is invalid,
+// it is for constant expressions in Go code.
+func MustConditionExpr(expression string) LinkOption {
+	opt, err := WithConditionExpr(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return opt
+}
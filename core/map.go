@@ -0,0 +1,38 @@
+package core
+
+import (
+	"github.com/zenmodel/zenmodel/internal/utils"
+)
+
+const (
+	// NeuronLabelMapList is the label key of map neuron, the value is the memory key of the list to map over
+	NeuronLabelMapList = "map_list"
+	// NeuronLabelMapResult is the label key of the memory key which map neuron stores the results to
+	NeuronLabelMapResult = "map_result"
+)
+
+const (
+	// MapItemMemoryKey is the memory key of current item, for processor of map neuron
+	MapItemMemoryKey = "__MAP_ITEM__"
+	// MapIndexMemoryKey is the memory key of the index of current item, for processor of map neuron
+	MapIndexMemoryKey = "__MAP_INDEX__"
+	// MapResultMemoryKey is the memory key which processor of map neuron sets the result of current item to
+	MapResultMemoryKey = "__MAP_RESULT__"
+)
+
+// WithMap makes Neuron a map neuron. When activated, it reads the list from memory by listKey and processes
+// each item as a parallel activation in its worker pool. Each item has its own scoped memory: GetMemory of
+// MapItemMemoryKey and MapIndexMemoryKey returns the item and its index, memories set by the processor are only
+// visible to the item, and other keys fall back to brain memory. After all items processed, the results set by
+// MapResultMemoryKey (or SetOutput of item) are stored to brain memory by resultKey as []any in item order, and
+// are the payload of its out-links, then Neuron casts as usual, so that a reduce neuron linked after it can join the results.
+// The map neuron fails if any item fails, a sub-blueprint can be mapped by a processor which runs a nested brain.
+func WithMap(listKey, resultKey string) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		labels := map[string]string{
+			NeuronLabelMapList:   listKey,
+			NeuronLabelMapResult: resultKey,
+		}
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), labels))
+	})
+}
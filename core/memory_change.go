@@ -0,0 +1,15 @@
+package core
+
+// MemoryChange is a change of memory sent to watchers, see Brain.WatchMemory
+type MemoryChange struct {
+	// Key is the memory key, it is nil if all memories are cleared and the memory can not list its keys, e.g. ristretto cache of BrainLocal
+	Key any
+	// Old is the value before change, it is nil if memory did not exist
+	Old any
+	// New is the value after change, it is nil if memory is deleted
+	New any
+	// Deleted is true if memory is deleted or cleared
+	Deleted bool
+	// NeuronID is the ID of neuron which wrote memory, it is empty if memory is written outside of neurons
+	NeuronID string
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"strconv"
+
 	"github.com/zenmodel/zenmodel/internal/utils"
 	"github.com/zenmodel/zenmodel/processor"
 )
@@ -9,6 +11,20 @@ const (
 	EndNeuronID = "__END_NEURON__"
 )
 
+const (
+	// NeuronLabelPriority is the label key of neuron scheduling priority, the value is an integer and default is 0
+	NeuronLabelPriority = "priority"
+	// NeuronLabelWorkerPool is the label key of neuron worker pool, e.g. `pool=io` or `pool=llm`
+	NeuronLabelWorkerPool = "pool"
+	// NeuronLabelMaxActivations is the label key of max activations of neuron in a run, 0 means unlimited
+	NeuronLabelMaxActivations = "max_activations"
+	// NeuronLabelLimitCastGroup is the label key of cast group which neuron casts to instead of activation,
+	// when a loop limit is hit. If it is not set, the run ends with *LimitError.
+	NeuronLabelLimitCastGroup = "limit_cast_group"
+	// NeuronLabelSelectorExpr is the label key of cast group selector expression, see WithSelectorExpr
+	NeuronLabelSelectorExpr = "selector_expr"
+)
+
 type NeuronState string
 
 const (
@@ -24,10 +40,14 @@ type Neuron interface {
 	ListInLinkIDs() []string
 	ListOutLinkIDs() []string
 	ListTriggerGroups() map[string][]string
+	// ListTriggerGroupSpecs returns the specs of trigger groups declared with options, key is group ID
+	ListTriggerGroupSpecs() map[string]TriggerGroupSpec
 	ListCastGroups() map[string][]string
 
 	SetLabels(labels map[string]string)
 	AddTriggerGroup(links ...Link) error
+	// AddTriggerGroupWithOptions is AddTriggerGroup with join semantics, e.g. WithQuorum, WithTriggerTimeout, WithFirstWins
+	AddTriggerGroupWithOptions(links []Link, opts ...TriggerGroupOption) error
 	AddCastGroup(groupName string, links ...Link) error
 	BindCastGroupSelectFunc(selectFn func(bcr processor.BrainContextReader) string)
 	BindCastGroupSelector(selector processor.Selector)
@@ -67,10 +87,68 @@ func WithSelector(selector processor.Selector) NeuronOption {
 	})
 }
 
+// WithSelectorExpr sets the selector of Neuron by expression evaluated over memory, see processor.NewExprSelector.
+// The expression is recorded in labels, so that it can be declared in blueprint config. It returns error if
+// expression is invalid.
+func WithSelectorExpr(expression string) (NeuronOption, error) {
+	selector, err := processor.NewExprSelector(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return neuronOptionFunc(func(neuron Neuron) {
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), map[string]string{NeuronLabelSelectorExpr: expression}))
+		neuron.BindCastGroupSelector(selector)
+	}), nil
+}
+
+// MustSelectorExpr is like WithSelectorExpr but panics if expression is invalid,
+// it is for constant expressions in Go code.
+func MustSelectorExpr(expression string) NeuronOption {
+	opt, err := WithSelectorExpr(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return opt
+}
+
 // WithPyProcessExecCmd sets the specific python command for Neuron
 func WithPyProcessExecCmd(pythonCmd string) NeuronOption {
 	return neuronOptionFunc(func(neuron Neuron) {
 		origin := neuron.GetLabels()
 		neuron.SetLabels(utils.MergeLabels(origin, map[string]string{"python_cmd": pythonCmd}))
 	})
-}
\ No newline at end of file
+}
+
+// WithPriority sets the scheduling priority for Neuron, activated neuron with higher priority is processed first
+func WithPriority(priority int) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		origin := neuron.GetLabels()
+		neuron.SetLabels(utils.MergeLabels(origin, map[string]string{NeuronLabelPriority: strconv.Itoa(priority)}))
+	})
+}
+
+// WithWorkerPool sets the worker pool for Neuron, the pool should be configured when building brain,
+// otherwise the default pool is used
+func WithWorkerPool(pool string) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		origin := neuron.GetLabels()
+		neuron.SetLabels(utils.MergeLabels(origin, map[string]string{NeuronLabelWorkerPool: pool}))
+	})
+}
+
+// WithMaxActivations sets the max activations of Neuron in a run, it stops runaway cycles
+func WithMaxActivations(max int) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), map[string]string{NeuronLabelMaxActivations: strconv.Itoa(max)}))
+	})
+}
+
+// WithLimitCastGroup sets the cast group which Neuron casts to instead of activation when a loop limit is hit,
+// e.g. a group links to a summary neuron or END.
+func WithLimitCastGroup(groupName string) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), map[string]string{NeuronLabelLimitCastGroup: groupName}))
+	})
+}
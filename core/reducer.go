@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Reducer combines the old value of memory with the new value set by SetMemory, old is nil if memory does not exist.
+// Brain applies reducer of key atomically, so that parallel neurons can contribute to the same memory safely,
+// e.g. fan-out branches append to `messages`. Reducers are registered by Blueprint.AddReducer or brain option.
+type Reducer func(old, new any) (any, error)
+
+// AppendReducer appends new to old list, new is appended as items if it is a list, otherwise as one item.
+// The result keeps the list type if items fit, otherwise it is []any.
+func AppendReducer(old, new any) (any, error) {
+	if old == nil {
+		if isList(new) {
+			return new, nil
+		}
+		return []any{new}, nil
+	}
+	o := reflect.ValueOf(old)
+	if !isList(old) {
+		return nil, fmt.Errorf("append reducer: old value %T is not a list", old)
+	}
+
+	var items []reflect.Value
+	if isList(new) {
+		n := reflect.ValueOf(new)
+		for i := 0; i < n.Len(); i++ {
+			items = append(items, n.Index(i))
+		}
+	} else {
+		items = append(items, reflect.ValueOf(new))
+	}
+
+	elemType := o.Type().Elem()
+	fit := true
+	for _, item := range items {
+		if !item.IsValid() || !item.Type().AssignableTo(elemType) {
+			fit = false
+			break
+		}
+	}
+	if fit && o.Kind() == reflect.Slice {
+		result := reflect.MakeSlice(o.Type(), 0, o.Len()+len(items))
+		result = reflect.AppendSlice(result, o)
+		return reflect.Append(result, items...).Interface(), nil
+	}
+
+	result := make([]any, 0, o.Len()+len(items))
+	for i := 0; i < o.Len(); i++ {
+		result = append(result, o.Index(i).Interface())
+	}
+	for _, item := range items {
+		if !item.IsValid() {
+			result = append(result, nil)
+			continue
+		}
+		result = append(result, item.Interface())
+	}
+
+	return result, nil
+}
+
+// MergeMapReducer merges new map into old map, values of new win. The result keeps the map type if entries fit,
+// otherwise it is map[string]any.
+func MergeMapReducer(old, new any) (any, error) {
+	n := reflect.ValueOf(new)
+	if n.Kind() != reflect.Map {
+		return nil, fmt.Errorf("merge map reducer: new value %T is not a map", new)
+	}
+	if old == nil {
+		return new, nil
+	}
+	o := reflect.ValueOf(old)
+	if o.Kind() != reflect.Map {
+		return nil, fmt.Errorf("merge map reducer: old value %T is not a map", old)
+	}
+
+	if n.Type().AssignableTo(o.Type()) {
+		result := reflect.MakeMapWithSize(o.Type(), o.Len()+n.Len())
+		for _, m := range []reflect.Value{o, n} {
+			iter := m.MapRange()
+			for iter.Next() {
+				result.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		return result.Interface(), nil
+	}
+
+	result := make(map[string]any, o.Len()+n.Len())
+	for _, m := range []reflect.Value{o, n} {
+		iter := m.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+		}
+	}
+
+	return result, nil
+}
+
+// MaxReducer keeps the greater number
+func MaxReducer(old, new any) (any, error) {
+	nf, ok := toFloat(new)
+	if !ok {
+		return nil, fmt.Errorf("max reducer: new value %T is not a number", new)
+	}
+	if old == nil {
+		return new, nil
+	}
+	of, ok := toFloat(old)
+	if !ok {
+		return nil, fmt.Errorf("max reducer: old value %T is not a number", old)
+	}
+	if nf > of {
+		return new, nil
+	}
+
+	return old, nil
+}
+
+func isList(v any) bool {
+	k := reflect.ValueOf(v).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"github.com/zenmodel/zenmodel/internal/utils"
+)
+
+const (
+	// NeuronLabelRetrigger is the label key of RetriggerPolicy, `ignore`, `queue` or `restart`
+	NeuronLabelRetrigger = "retrigger"
+)
+
+// RetriggerPolicy decides what to do when a trigger group of neuron is satisfied while the neuron is still active
+type RetriggerPolicy string
+
+const (
+	// RetriggerQueue keeps the new trigger, neuron is activated again after current process done.
+	// Triggers arrive on the same in-link during one process are merged into one activation.
+	RetriggerQueue RetriggerPolicy = "queue"
+	// RetriggerIgnore drops the new trigger, the in-links of the satisfied trigger group are reset
+	RetriggerIgnore RetriggerPolicy = "ignore"
+	// RetriggerRestart cancels current process through its BrainContext and activates neuron again immediately,
+	// e.g. a chat agent which gets a new user message while it is still generating
+	RetriggerRestart RetriggerPolicy = "restart"
+)
+
+// RetriggerPolicyFromLabels parses RetriggerPolicy from neuron labels, default is RetriggerQueue
+func RetriggerPolicyFromLabels(labels map[string]string) RetriggerPolicy {
+	switch p := RetriggerPolicy(labels[NeuronLabelRetrigger]); p {
+	case RetriggerIgnore, RetriggerRestart:
+		return p
+	default:
+		return RetriggerQueue
+	}
+}
+
+// WithRetriggerPolicy sets the RetriggerPolicy for Neuron
+func WithRetriggerPolicy(policy RetriggerPolicy) NeuronOption {
+	return neuronOptionFunc(func(neuron Neuron) {
+		neuron.SetLabels(utils.MergeLabels(neuron.GetLabels(), map[string]string{NeuronLabelRetrigger: string(policy)}))
+	})
+}
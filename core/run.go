@@ -0,0 +1,14 @@
+package core
+
+// RunResult is the result of a brain run, a new run starts every time brain turns from Sleeping to Running.
+type RunResult struct {
+	RunID string
+	// Err is not nil if the run ends because of error, e.g. *LimitError
+	Err error
+	// Outcome is the outcome name of the end link which ends the run, see WithOutcome.
+	// It is empty if the run does not end at END neuron, or the end link has no outcome
+	Outcome string
+	// Limit is the first loop limit hit in the run which is routed to the limit cast group of the offending neuron,
+	// see WithLimitCastGroup. It is nil if no limit is hit, or the run ends with the limit as Err
+	Limit *LimitError
+}
@@ -0,0 +1,54 @@
+package core
+
+// BrainStatus is a snapshot of brain, it is safe to be read while brain is running.
+type BrainStatus struct {
+	ID    string
+	State BrainState
+	// RunID is the ID of current run, a new run starts every time brain turns from Sleeping to Running.
+	// It is the ID of the last run when brain is Sleeping, empty if brain never run.
+	RunID string
+	// Neurons is the status of all neurons, key is neuron ID
+	Neurons map[string]NeuronStatus
+	// Links is the status of all links, key is link ID
+	Links map[string]LinkStatus
+	// Pending is the neuron activations waiting for workers, in the order they would be processed in each worker pool
+	Pending []PendingActivation
+	// MemoryKeys is the number of memories, it is 0 if memory is not initialized
+	MemoryKeys int
+	// MemoryBytes is the total size of memories, it is 0 if size is not accounted, e.g. BrainLocal without sizer
+	MemoryBytes int64
+}
+
+// NeuronStatus is a snapshot of neuron
+type NeuronStatus struct {
+	State NeuronState
+	// Seq is the sequence of the latest activation
+	Seq int
+	// RunID is the brain run ID of the latest activation
+	RunID string
+	// Process is the number of activations
+	Process int
+	// Succeed is the number of activations which processed successfully
+	Succeed int
+	// Failed is the number of activations which processed with error
+	Failed int
+	// Canceled is the number of activations which canceled by RetriggerRestart
+	Canceled int
+}
+
+// LinkStatus is a snapshot of link
+type LinkStatus struct {
+	State LinkState
+	From  string
+	To    string
+	// PendingCasts is the number of ContinueCast waiting for the link consumed
+	PendingCasts int
+}
+
+// PendingActivation is a neuron activation queued in worker pool
+type PendingActivation struct {
+	NeuronID string
+	Seq      int
+	Pool     string
+	Priority int
+}
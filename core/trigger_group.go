@@ -0,0 +1,45 @@
+package core
+
+import "time"
+
+// TriggerGroupSpec is the join semantics of trigger group, the zero value requires every link of group Ready.
+// When a group fires with part of its links Ready, the other links of group are late, their next cast is ignored,
+// so that a late link does not fire the group again. Late links are reset when brain sleeps.
+type TriggerGroupSpec struct {
+	// Quorum is the number of Ready links to fire the group, 0 or more than the size of group means all links
+	Quorum int
+	// Timeout fires the group with whatever is Ready, once Timeout passed after the first link of group is Ready,
+	// 0 means no timeout
+	Timeout time.Duration
+}
+
+// TriggerGroupOption configures a trigger group.
+type TriggerGroupOption interface {
+	Apply(spec *TriggerGroupSpec)
+}
+
+// triggerGroupOptionFunc wraps a func, so it satisfies the TriggerGroupOption interface.
+type triggerGroupOptionFunc func(*TriggerGroupSpec)
+
+func (f triggerGroupOptionFunc) Apply(spec *TriggerGroupSpec) {
+	f(spec)
+}
+
+// WithQuorum fires the trigger group when any k of its links are Ready
+func WithQuorum(k int) TriggerGroupOption {
+	return triggerGroupOptionFunc(func(spec *TriggerGroupSpec) {
+		spec.Quorum = k
+	})
+}
+
+// WithTriggerTimeout fires the trigger group with whatever is Ready, once timeout passed after the first link Ready
+func WithTriggerTimeout(timeout time.Duration) TriggerGroupOption {
+	return triggerGroupOptionFunc(func(spec *TriggerGroupSpec) {
+		spec.Timeout = timeout
+	})
+}
+
+// WithFirstWins fires the trigger group by the first Ready link and ignores the rest
+func WithFirstWins() TriggerGroupOption {
+	return WithQuorum(1)
+}
@@ -0,0 +1,436 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var builtins = map[string]func(env Env, args []any) (any, error){
+	"len":      builtinLen,
+	"has":      builtinHas,
+	"contains": builtinContains,
+	"lower":    builtinLower,
+	"upper":    builtinUpper,
+}
+
+func eval(n node, env Env) (any, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+	case *identNode:
+		return env.GetMemory(n.name), nil
+	case *listNode:
+		list := make([]any, 0, len(n.items))
+		for _, item := range n.items {
+			v, err := eval(item, env)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case *unaryNode:
+		return evalUnary(n, env)
+	case *binaryNode:
+		return evalBinary(n, env)
+	case *ternaryNode:
+		cond, err := evalBool(n.cond, env)
+		if err != nil {
+			return nil, err
+		}
+		if cond {
+			return eval(n.then, env)
+		}
+		return eval(n.otherwise, env)
+	case *memberNode:
+		target, err := eval(n.target, env)
+		if err != nil {
+			return nil, err
+		}
+		return member(target, n.name)
+	case *indexNode:
+		target, err := eval(n.target, env)
+		if err != nil {
+			return nil, err
+		}
+		index, err := eval(n.index, env)
+		if err != nil {
+			return nil, err
+		}
+		return indexOf(target, index)
+	case *callNode:
+		args := make([]any, 0, len(n.args))
+		for _, arg := range n.args {
+			v, err := eval(arg, env)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		}
+		return builtins[n.fn](env, args)
+	default:
+		return nil, fmt.Errorf("unknown node %T", n)
+	}
+}
+
+func evalBool(n node, env Env) (bool, error) {
+	v, err := eval(n, env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expect bool but got %T", v)
+	}
+	return b, nil
+}
+
+func evalUnary(n *unaryNode, env Env) (any, error) {
+	if n.op == "!" {
+		b, err := evalBool(n.operand, env)
+		return !b, err
+	}
+
+	v, err := eval(n.operand, env)
+	if err != nil {
+		return nil, err
+	}
+	i, f, isInt, ok := toNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("can not negate %T", v)
+	}
+	if isInt {
+		return -i, nil
+	}
+	return -f, nil
+}
+
+func evalBinary(n *binaryNode, env Env) (any, error) {
+	// short circuit
+	switch n.op {
+	case "&&", "||":
+		left, err := evalBool(n.left, env)
+		if err != nil {
+			return nil, err
+		}
+		if left == (n.op == "||") {
+			return left, nil
+		}
+		return evalBool(n.right, env)
+	}
+
+	left, err := eval(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := eval(n.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	default:
+		return arithmetic(n.op, left, right)
+	}
+}
+
+func arithmetic(op string, left, right any) (any, error) {
+	if ls, ok := left.(string); ok && op == "+" {
+		if rs, ok := right.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	li, lf, lInt, lok := toNumber(left)
+	ri, rf, rInt, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("invalid operation %T %s %T", left, op, right)
+	}
+
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		return lf / rf, nil
+	case "%":
+		return math.Mod(lf, rf), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+func compare(op string, left, right any) (bool, error) {
+	var c int
+	_, lf, _, lok := toNumber(left)
+	_, rf, _, rok := toNumber(right)
+	ls, lsok := left.(string)
+	rs, rsok := right.(string)
+	switch {
+	case lok && rok:
+		c = compareOrdered(lf, rf)
+	case lsok && rsok:
+		c = strings.Compare(ls, rs)
+	default:
+		return false, fmt.Errorf("invalid comparison %T %s %T", left, op, right)
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// equal compares numbers by value regardless of their types, e.g. int 1 equals float64 1 read from JSON memory
+func equal(left, right any) bool {
+	if isNil(left) || isNil(right) {
+		return isNil(left) && isNil(right)
+	}
+	_, lf, _, lok := toNumber(left)
+	_, rf, _, rok := toNumber(right)
+	if lok && rok {
+		return lf == rf
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+// contains reports whether item is in list, key of map, or substring of string
+func contains(container, item any) (bool, error) {
+	if s, ok := container.(string); ok {
+		sub, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("invalid operation %T in string", item)
+		}
+		return strings.Contains(s, sub), nil
+	}
+
+	v := indirect(reflect.ValueOf(container))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return false, nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(v.Index(i).Interface(), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := mapKey(v, item)
+		return ok && v.MapIndex(key).IsValid(), nil
+	default:
+		return false, fmt.Errorf("invalid operation in %T", container)
+	}
+}
+
+// member gets field of struct by name, JSON tag or snake case name, or value of map by key.
+// Member of nil is nil, so that a missing memory does not fail the expression.
+func member(target any, name string) (any, error) {
+	v := indirect(reflect.ValueOf(target))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Map:
+		return indexOf(target, name)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.Name == name || tag == name || strings.EqualFold(f.Name, strings.ReplaceAll(name, "_", "")) {
+				return v.Field(i).Interface(), nil
+			}
+		}
+		return nil, fmt.Errorf("%s has no field %s", t, name)
+	default:
+		return nil, fmt.Errorf("can not get field %s of %T", name, target)
+	}
+}
+
+// indexOf gets item of list or string by index, negative index counts from the end, or value of map by key.
+// Out of range index and missing key are nil.
+func indexOf(target, index any) (any, error) {
+	v := indirect(reflect.ValueOf(target))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Map:
+		key, ok := mapKey(v, index)
+		if !ok {
+			return nil, nil
+		}
+		if item := v.MapIndex(key); item.IsValid() {
+			return item.Interface(), nil
+		}
+		return nil, nil
+	case reflect.Struct:
+		name, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid index %T of %T", index, target)
+		}
+		return member(target, name)
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, _, isInt, ok := toNumber(index)
+		if !ok || !isInt {
+			return nil, fmt.Errorf("invalid index %T of %T", index, target)
+		}
+		if i < 0 {
+			i += v.Len()
+		}
+		if i < 0 || i >= v.Len() {
+			return nil, nil
+		}
+		if v.Kind() == reflect.String {
+			return v.String()[i : i+1], nil
+		}
+		return v.Index(i).Interface(), nil
+	default:
+		return nil, fmt.Errorf("can not index %T", target)
+	}
+}
+
+func mapKey(m reflect.Value, key any) (reflect.Value, bool) {
+	k := reflect.ValueOf(key)
+	if !k.IsValid() {
+		return k, false
+	}
+	if k.Type().AssignableTo(m.Type().Key()) {
+		return k, true
+	}
+	if k.Type().ConvertibleTo(m.Type().Key()) && k.Kind() == m.Type().Key().Kind() {
+		return k.Convert(m.Type().Key()), true
+	}
+	return k, false
+}
+
+// toNumber converts integers to int and floats to float64, f is always set for comparison
+func toNumber(v any) (i int, f float64, isInt bool, ok bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), float64(rv.Int()), true, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), float64(rv.Uint()), true, true
+	case reflect.Float32, reflect.Float64:
+		return int(rv.Float()), rv.Float(), false, true
+	default:
+		return 0, 0, false, false
+	}
+}
+
+// indirect dereferences pointers and interfaces, it returns invalid value for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isNil(v any) bool {
+	return !indirect(reflect.ValueOf(v)).IsValid()
+}
+
+func builtinLen(_ Env, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("len expects 1 argument but got %d", len(args))
+	}
+	v := indirect(reflect.ValueOf(args[0]))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0, nil
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return v.Len(), nil
+	default:
+		return nil, fmt.Errorf("invalid argument %T for len", args[0])
+	}
+}
+
+func builtinHas(env Env, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("has expects 1 argument but got %d", len(args))
+	}
+	return env.ExistMemory(args[0]), nil
+}
+
+func builtinContains(_ Env, args []any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("contains expects 2 arguments but got %d", len(args))
+	}
+	return contains(args[0], args[1])
+}
+
+func builtinLower(_ Env, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("lower expects 1 argument but got %d", len(args))
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument %T for lower", args[0])
+	}
+	return strings.ToLower(s), nil
+}
+
+func builtinUpper(_ Env, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("upper expects 1 argument but got %d", len(args))
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument %T for upper", args[0])
+	}
+	return strings.ToUpper(s), nil
+}
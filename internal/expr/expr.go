@@ -0,0 +1,80 @@
+// Package expr is a small expression language evaluated over brain memory, it is used by expression selectors and
+// link conditions so that routing can be declared as strings, e.g.
+//
+//	len(messages[-1].tool_calls) > 0 ? "continue" : "end"
+//
+// Identifiers are memory keys, keys which are keywords or not identifiers can be quoted by backticks, e.g. `user-name`.
+// It supports number, single or double-quoted string, bool, nil and list literals, `.field` and `[index]`
+// access (negative index counts from the end, field matches struct field name, JSON tag or snake case name),
+// arithmetic `+ - * / %`, comparison `== != < <= > >=`, `in`, logic `! && ||`, ternary `?:` and functions
+// `len`, `has` (memory exists), `contains`, `lower`, `upper`. Missing values are nil instead of errors.
+package expr
+
+import (
+	"fmt"
+)
+
+// Env is the memory which expression is evaluated over
+type Env interface {
+	GetMemory(key interface{}) interface{}
+	ExistMemory(key interface{}) bool
+}
+
+// Program is a compiled expression, it is safe for concurrent use
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile parses expression into Program
+func Compile(expression string) (*Program, error) {
+	root, err := parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("compile expression %q: %w", expression, err)
+	}
+
+	return &Program{source: expression, root: root}, nil
+}
+
+// Source returns the expression of Program
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Run evaluates Program over env
+func (p *Program) Run(env Env) (any, error) {
+	v, err := eval(p.root, env)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate expression %q: %w", p.source, err)
+	}
+
+	return v, nil
+}
+
+// RunString evaluates Program which results in string
+func (p *Program) RunString(env Env) (string, error) {
+	v, err := p.Run(env)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("evaluate expression %q: expect string but got %T", p.source, v)
+	}
+
+	return s, nil
+}
+
+// RunBool evaluates Program which results in bool
+func (p *Program) RunBool(env Env) (bool, error) {
+	v, err := p.Run(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluate expression %q: expect bool but got %T", p.source, v)
+	}
+
+	return b, nil
+}
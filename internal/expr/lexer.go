@@ -0,0 +1,151 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	// text is the source text of token, it is the unquoted value of string token and quoted identifier
+	text string
+	pos  int
+	// quoted is true if identifier is quoted by backticks, it is never a keyword or function
+	quoted bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// operators longer ones first, so that `<=` is not lexed as `<` and `=`
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"!", "<", ">", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]",
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(rune(src[i])) || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(rune(src[i])) || isDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: i})
+			i += n
+		case c == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end <= 0 {
+				return nil, fmt.Errorf("invalid quoted identifier at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i+1 : i+1+end], pos: i, quoted: true})
+			i += end + 2
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString lexes a quoted string at the beginning of src, it returns the unquoted value and the length in src
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			raw := src[1:i]
+			if quote == '\'' { // unquote single-quoted string as double-quoted one
+				raw = doubleQuoted(raw)
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", src[:i+1])
+			}
+			return s, i + 1, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// doubleQuoted converts the content of single-quoted string to the content of double-quoted one,
+// `\'` is unescaped and bare `"` is escaped, other escapes are kept
+func doubleQuoted(raw string) string {
+	var sb strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			if i+1 < len(raw) && raw[i+1] == '\'' {
+				sb.WriteByte('\'')
+			} else if i+1 < len(raw) {
+				sb.WriteString(raw[i : i+2])
+			}
+			i++
+		case '"':
+			sb.WriteString(`\"`)
+		default:
+			sb.WriteByte(raw[i])
+		}
+	}
+
+	return sb.String()
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,281 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type node interface{}
+
+type (
+	literalNode struct {
+		value any
+	}
+	identNode struct {
+		name string
+	}
+	listNode struct {
+		items []node
+	}
+	unaryNode struct {
+		op      string
+		operand node
+	}
+	binaryNode struct {
+		op          string
+		left, right node
+	}
+	ternaryNode struct {
+		cond, then, otherwise node
+	}
+	memberNode struct {
+		target node
+		name   string
+	}
+	indexNode struct {
+		target, index node
+	}
+	callNode struct {
+		fn   string
+		args []node
+	}
+)
+
+// binary operators by precedence, from low to high
+var precedences = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword op
+func (p *parser) accept(op string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent && !t.quoted) && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expect %q but got %s at %d", op, t, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ternaryNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedences) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptAny(precedences[level])
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) acceptAny(ops []string) (string, bool) {
+	for _, op := range ops {
+		if p.accept(op) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.acceptAny([]string{"!", "-"}); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expect field name but got %s at %d", t, t.pos)
+			}
+			n = &memberNode{target: n, name: t.text}
+		case p.accept("["):
+			index, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		if !strings.ContainsAny(t.text, ".eE") {
+			if v, err := strconv.Atoi(t.text); err == nil {
+				return &literalNode{value: v}, nil
+			}
+		}
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d", t, t.pos)
+		}
+		return &literalNode{value: v}, nil
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdent:
+		if t.quoted {
+			return &identNode{name: t.text}, nil
+		}
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil", "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			if _, ok := builtins[t.text]; !ok {
+				return nil, fmt.Errorf("unknown function %s at %d", t, t.pos)
+			}
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{fn: t.text, args: args}, nil
+		}
+		return &identNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			n, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+}
+
+// parseList parses comma separated expressions until the closing operator
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.accept(closing) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(closing) {
+			return items, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
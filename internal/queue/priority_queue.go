@@ -0,0 +1,163 @@
+package queue
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PriorityQueue is an unbounded priority queue with aging which is safe for concurrent use.
+// Item with higher priority pops first, and the priority of waiting item increases by 1 every aging interval,
+// so that low priority items will not starve. Items with the same effective priority pop in FIFO order.
+type PriorityQueue[T any] struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  priorityItems[T]
+	aging  time.Duration
+	start  time.Time
+	seq    uint64
+	closed bool
+}
+
+// NewPriority creates a priority queue, capacity is only the initial size of the underlying buffer,
+// aging <= 0 means no aging.
+func NewPriority[T any](capacity int, aging time.Duration) *PriorityQueue[T] {
+	if capacity < 0 {
+		capacity = 0
+	}
+	q := &PriorityQueue[T]{
+		items: make(priorityItems[T], 0, capacity),
+		aging: aging,
+		start: time.Now(),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// Push adds an item with priority, it returns false if the queue is closed.
+func (q *PriorityQueue[T]) Push(item T, priority int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	q.seq++
+	heap.Push(&q.items, &priorityItem[T]{
+		value: item,
+		key:   q.key(priority),
+		seq:   q.seq,
+	})
+	q.cond.Signal()
+
+	return true
+}
+
+// Pop removes and returns the item with the highest effective priority, it blocks until an item is available.
+// It returns false once the queue is closed, items remained in queue are discarded.
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	var zero T
+	if q.closed {
+		return zero, false
+	}
+	item := heap.Pop(&q.items).(*priorityItem[T])
+
+	return item.value, true
+}
+
+// Len returns the number of items in queue.
+func (q *PriorityQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// Items returns a copy of items in queue, in the order they would pop.
+func (q *PriorityQueue[T]) Items() []T {
+	q.mu.Lock()
+	sorted := append(priorityItems[T](nil), q.items...)
+	q.mu.Unlock()
+
+	sort.Sort(sorted)
+	values := make([]T, 0, len(sorted))
+	for _, item := range sorted {
+		values = append(values, item.value)
+	}
+
+	return values
+}
+
+// Close closes the queue and wakes up all blocked Pop, it returns the number of discarded items.
+func (q *PriorityQueue[T]) Close() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	discarded := len(q.items)
+	q.closed = true
+	q.items = nil
+	q.cond.Broadcast()
+
+	return discarded
+}
+
+// key is the static sort key of item. The effective priority of item at time now is
+// priority + (now - enqueued) / aging, comparing two items the `now` cancels out,
+// so priority - enqueued / aging is enough for ordering and never changes while waiting.
+// enqueued is measured from the creation of queue rather than Unix epoch, otherwise float64 loses
+// the precision of priority when aging is small.
+func (q *PriorityQueue[T]) key(priority int) float64 {
+	if q.aging <= 0 {
+		return float64(priority)
+	}
+
+	return float64(priority) - float64(time.Since(q.start).Nanoseconds())/float64(q.aging.Nanoseconds())
+}
+
+type priorityItem[T any] struct {
+	value T
+	key   float64
+	seq   uint64
+}
+
+// priorityItems implements heap.Interface
+type priorityItems[T any] []*priorityItem[T]
+
+func (items priorityItems[T]) Len() int {
+	return len(items)
+}
+
+func (items priorityItems[T]) Less(i, j int) bool {
+	if items[i].key != items[j].key {
+		return items[i].key > items[j].key
+	}
+
+	return items[i].seq < items[j].seq
+}
+
+func (items priorityItems[T]) Swap(i, j int) {
+	items[i], items[j] = items[j], items[i]
+}
+
+func (items *priorityItems[T]) Push(x any) {
+	*items = append(*items, x.(*priorityItem[T]))
+}
+
+func (items *priorityItems[T]) Pop() any {
+	old := *items
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	*items = old[:n-1]
+
+	return item
+}
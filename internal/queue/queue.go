@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"sync"
+)
+
+// Queue is an unbounded FIFO queue which is safe for concurrent use.
+// Push never blocks, so a consumer can publish into the queue it is consuming without deadlock.
+type Queue[T any] struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []T
+	closed bool
+}
+
+// New creates a queue, capacity is only the initial size of the underlying buffer.
+func New[T any](capacity int) *Queue[T] {
+	if capacity < 0 {
+		capacity = 0
+	}
+	q := &Queue[T]{
+		items: make([]T, 0, capacity),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
+
+// Push appends an item to the tail of queue, it returns false if the queue is closed.
+func (q *Queue[T]) Push(item T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+
+	return true
+}
+
+// Pop removes and returns the head of queue, it blocks until an item is available.
+// It returns false once the queue is closed, items remained in queue are discarded.
+func (q *Queue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	var zero T
+	if q.closed {
+		return zero, false
+	}
+	item := q.items[0]
+	q.items[0] = zero // avoid memory leak
+	q.items = q.items[1:]
+
+	return item, true
+}
+
+// Len returns the number of items in queue.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// Close closes the queue and wakes up all blocked Pop.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.items = nil
+	q.cond.Broadcast()
+}
@@ -42,6 +42,8 @@ type link struct {
 	src string
 	// to destination neuron ID
 	dest string
+	// condition is the predicate of conditional link
+	condition core.LinkCondition
 }
 
 func (l *link) GetSrcNeuronID() string {
@@ -64,6 +66,14 @@ func (l *link) SetLabels(labels map[string]string) {
 	l.labels = labels
 }
 
+func (l *link) GetCondition() core.LinkCondition {
+	return l.condition
+}
+
+func (l *link) SetCondition(condition core.LinkCondition) {
+	l.condition = condition
+}
+
 func (l *link) IsEntryLink() bool {
 	return l.src == core.EntryLinkFrom
 }
@@ -74,10 +84,11 @@ func (l *link) IsEndLink() bool {
 
 func (l *link) deepCopy() *link {
 	return &link{
-		id:     l.id,
-		labels: utils.LabelsDeepCopy(l.labels),
-		src:    l.src,
-		dest:   l.dest,
+		id:        l.id,
+		labels:    utils.LabelsDeepCopy(l.labels),
+		src:       l.src,
+		dest:      l.dest,
+		condition: l.condition,
 	}
 }
 
@@ -85,5 +96,6 @@ func (l *link) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("id", l.id).
 		Any("labels", l.labels).
 		Str("src", l.src).
-		Str("dest", l.dest)
+		Str("dest", l.dest).
+		Bool("conditional", l.condition != nil)
 }
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RegisteredTypePrefix prefixes the name of registered type in the value type of EncodeValue. Readers which do not
+// know the type, e.g. Python processors, read the value as JSON
+const RegisteredTypePrefix = "go:"
+
+// typeRegistry maps names to registered types, it is shared by all brains like gob.Register
+var typeRegistry = struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}{
+	types: make(map[string]reflect.Type),
+	names: make(map[reflect.Type]string),
+}
+
+// RegisterType registers the type of value by its full name, e.g. "[]github.com/sashabaranov/go-openai.ChatCompletionMessage".
+// Values of registered type are encoded as JSON tagged with the type name, and decoded as the same type
+// instead of map[string]any or []any, e.g. memories of BrainLite and memory snapshots. Only the type of value itself
+// is restored, values nested in unregistered maps or lists are not. Register types in init, before they are decoded.
+func RegisterType(value any) {
+	RegisterTypeName(typeName(reflect.TypeOf(value)), value)
+}
+
+// RegisterTypeName registers the type of value by name, name keeps encoded values readable after the type
+// is renamed or moved. It panics if name or type is registered already with another type or name.
+func RegisterTypeName(name string, value any) {
+	t := reflect.TypeOf(value)
+	if name == "" || t == nil {
+		panic("memory: register type with empty name or nil value")
+	}
+
+	typeRegistry.mu.Lock()
+	defer typeRegistry.mu.Unlock()
+	if registered, ok := typeRegistry.types[name]; ok && registered != t {
+		panic(fmt.Sprintf("memory: registering duplicate types for %q: %s != %s", name, registered, t))
+	}
+	if registered, ok := typeRegistry.names[t]; ok && registered != name {
+		panic(fmt.Sprintf("memory: registering duplicate names for %s: %q != %q", t, registered, name))
+	}
+	typeRegistry.types[name] = t
+	typeRegistry.names[t] = name
+}
+
+// EncodeValue encodes value to JSON, and classifies its type so that DecodeValue restores numbers, bools and
+// registered types. The type is one of string, int, uint, float, bool, json, or RegisteredTypePrefix with type name
+func EncodeValue(value any) ([]byte, string, error) {
+	var valueType string
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		valueType = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		valueType = "int"
+	case reflect.Uint, reflect.Uint64:
+		valueType = "uint"
+	case reflect.Float32, reflect.Float64:
+		valueType = "float"
+	case reflect.Bool:
+		valueType = "bool"
+	default:
+		valueType = "json"
+	}
+	if name, ok := registeredName(value); ok {
+		valueType = name
+	}
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, "", fmt.Errorf("encode %T: %w", value, err)
+	}
+
+	return valueJSON, valueType, nil
+}
+
+// DecodeValue decodes JSON by the type classified by EncodeValue, ints are always decoded as int and uints as uint64.
+// Registered types unknown to current process are decoded as JSON
+func DecodeValue(valueJSON []byte, valueType string) (any, error) {
+	value, registered, err := decodeRegistered(valueJSON, valueType)
+	if registered {
+		return value, err
+	}
+
+	switch valueType {
+	case "string":
+		err = json.Unmarshal(valueJSON, &value)
+	case "int":
+		// always int whatever the int type set, so that the decoded type does not depend on the value
+		var intValue int
+		err = json.Unmarshal(valueJSON, &intValue)
+		value = intValue
+	case "uint":
+		var uintValue uint64
+		err = json.Unmarshal(valueJSON, &uintValue)
+		value = uintValue
+	case "float":
+		var floatValue float64
+		err = json.Unmarshal(valueJSON, &floatValue)
+		value = floatValue
+	case "bool":
+		var boolValue bool
+		err = json.Unmarshal(valueJSON, &boolValue)
+		value = boolValue
+	default:
+		// json, or registered type unknown to current process
+		err = json.Unmarshal(valueJSON, &value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", valueType, err)
+	}
+
+	return value, nil
+}
+
+// registeredName returns the value type of registered type of value
+func registeredName(value any) (string, bool) {
+	t := reflect.TypeOf(value)
+	if t == nil {
+		return "", false
+	}
+	typeRegistry.mu.RLock()
+	defer typeRegistry.mu.RUnlock()
+	name, ok := typeRegistry.names[t]
+
+	return RegisteredTypePrefix + name, ok
+}
+
+// decodeRegistered decodes JSON of registered type, ok is false if valueType is not a registered type,
+// e.g. the type is registered by another process
+func decodeRegistered(valueJSON []byte, valueType string) (value any, ok bool, err error) {
+	name := strings.TrimPrefix(valueType, RegisteredTypePrefix)
+	if name == valueType {
+		return nil, false, nil
+	}
+	typeRegistry.mu.RLock()
+	t, ok := typeRegistry.types[name]
+	typeRegistry.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	v := reflect.New(t)
+	if err = json.Unmarshal(valueJSON, v.Interface()); err != nil {
+		return nil, true, fmt.Errorf("decode %s: %w", valueType, err)
+	}
+
+	return v.Elem().Interface(), true, nil
+}
+
+// typeName names type by its package path, so that types of the same name in different packages differ
+func typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		return t.PkgPath() + "." + t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", typeName(t.Key()), typeName(t.Elem()))
+	}
+
+	return t.String()
+}
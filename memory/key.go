@@ -0,0 +1,31 @@
+// Package memory provides typed access to brain memory, so that callers do not type-assert the values of GetMemory.
+//
+//	var Answer = memory.NewKey[string]("answer")
+//
+//	func process(bc processor.BrainContext) error {
+//		question, ok, err := memory.Get(bc, Question)
+//		...
+//		return memory.Set(bc, Answer, answer)
+//	}
+//
+// The helpers work against processor.BrainContext, processor.BrainContextReader and brains.
+package memory
+
+// Key is a memory key whose value is of type T
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates Key of type T by the name of memory
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// Name returns the name of memory
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+func (k Key[T]) String() string {
+	return k.name
+}
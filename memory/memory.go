@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+// Reader reads memory, it is satisfied by processor.BrainContextReader, processor.BrainContext and brains
+type Reader interface {
+	GetMemory(key interface{}) interface{}
+	ExistMemory(key interface{}) bool
+}
+
+// Writer writes memory, it is satisfied by processor.BrainContext and brains
+type Writer interface {
+	SetMemory(keysAndValues ...interface{}) error
+}
+
+// ReadWriter reads and writes memory
+type ReadWriter interface {
+	Reader
+	Writer
+}
+
+// Updater runs fn in a memory transaction, it is satisfied by processor.BrainContext and brains
+type Updater interface {
+	UpdateMemory(fn func(tx processor.MemoryTx) error) error
+}
+
+// Decoder is implemented by memory which stores encoded values, e.g. SQLite memory of brainlite.
+// Get decodes the stored value into the type of key directly, instead of converting the decoded value.
+type Decoder interface {
+	// DecodeMemory decodes the memory of key into target which is a pointer, ok is false if memory not found
+	DecodeMemory(key interface{}, target interface{}) (ok bool, err error)
+}
+
+// Get reads the memory of key. It returns false if the memory does not exist,
+// and error if the memory can not be converted to T.
+func Get[T any](r Reader, key Key[T]) (T, bool, error) {
+	var v T
+	if d, ok := r.(Decoder); ok {
+		found, err := d.DecodeMemory(key.name, &v)
+		if err != nil {
+			var zero T
+			return zero, found, fmt.Errorf("get memory %s: %w", key.name, err)
+		}
+		return v, found, nil
+	}
+
+	if !r.ExistMemory(key.name) {
+		return v, false, nil
+	}
+	if err := Convert(r.GetMemory(key.name), &v); err != nil {
+		return v, true, fmt.Errorf("get memory %s: %w", key.name, err)
+	}
+
+	return v, true, nil
+}
+
+// Set writes the memory of key
+func Set[T any](w Writer, key Key[T], value T) error {
+	return w.SetMemory(key.name, value)
+}
+
+// Update reads the memory of key, and writes the value returned by fn. ok is false if the memory does not exist.
+// It is atomic if rw is an Updater, otherwise concurrent updates of the same key may overwrite each other.
+func Update[T any](rw ReadWriter, key Key[T], fn func(old T, ok bool) (T, error)) error {
+	if u, ok := rw.(Updater); ok {
+		return u.UpdateMemory(func(tx processor.MemoryTx) error {
+			return update(tx, key, fn)
+		})
+	}
+
+	return update(rw, key, fn)
+}
+
+func update[T any](rw ReadWriter, key Key[T], fn func(old T, ok bool) (T, error)) error {
+	old, ok, err := Get(rw, key)
+	if err != nil {
+		return err
+	}
+	v, err := fn(old, ok)
+	if err != nil {
+		return err
+	}
+
+	return Set(rw, key, v)
+}
+
+// Convert sets value to target which is a pointer. Value of the same type is assigned, numbers are converted,
+// other values are converted through JSON, e.g. map[string]any decoded from JSON memory to struct.
+func Convert(value interface{}, target interface{}) error {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Ptr || t.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	elem := t.Elem()
+	if value == nil {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(elem.Type()) {
+		elem.Set(v)
+		return nil
+	}
+	if isNumber(v.Kind()) && isNumber(elem.Kind()) {
+		converted := v.Convert(elem.Type())
+		// the number is out of range of integer or has fraction
+		if !isFloat(elem.Kind()) && !reflect.DeepEqual(converted.Convert(v.Type()).Interface(), value) {
+			return fmt.Errorf("can not convert %v to %s", value, elem.Type())
+		}
+		elem.Set(converted)
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("can not convert %T to %s: %w", value, elem.Type(), err)
+	}
+	if err = json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("can not convert %T to %s: %w", value, elem.Type(), err)
+	}
+
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SnapshotVersion is the version of snapshot written by WriteSnapshot
+const SnapshotVersion = 1
+
+// Snapshot is the portable JSON of memories exported by brains, it can be imported into brain of any kind.
+// Keys and values are encoded by EncodeValue, so that numbers, bools and registered types are restored.
+//
+//	{"version": 1, "memories": [{"key": "answer", "key_type": "string", "value": 42, "value_type": "int"}]}
+type Snapshot struct {
+	Version  int             `json:"version"`
+	Memories []SnapshotEntry `json:"memories"`
+}
+
+// SnapshotEntry is one memory of Snapshot
+type SnapshotEntry struct {
+	Key       json.RawMessage `json:"key"`
+	KeyType   string          `json:"key_type"`
+	Value     json.RawMessage `json:"value"`
+	ValueType string          `json:"value_type"`
+}
+
+// NewSnapshotEntry encodes memory of key, []byte key is the same key as string
+func NewSnapshotEntry(key, value any) (SnapshotEntry, error) {
+	if k, ok := key.([]byte); ok {
+		key = string(k)
+	}
+	keyJSON, keyType, err := EncodeValue(key)
+	if err != nil {
+		return SnapshotEntry{}, fmt.Errorf("key %v: %w", key, err)
+	}
+	valueJSON, valueType, err := EncodeValue(value)
+	if err != nil {
+		return SnapshotEntry{}, fmt.Errorf("memory %v: %w", key, err)
+	}
+
+	return SnapshotEntry{Key: keyJSON, KeyType: keyType, Value: valueJSON, ValueType: valueType}, nil
+}
+
+// Decode decodes key and value of entry
+func (e SnapshotEntry) Decode() (key, value any, err error) {
+	if key, err = DecodeValue(e.Key, e.KeyType); err != nil {
+		return nil, nil, fmt.Errorf("key %s: %w", e.Key, err)
+	}
+	if value, err = DecodeValue(e.Value, e.ValueType); err != nil {
+		return nil, nil, fmt.Errorf("memory %s: %w", e.Key, err)
+	}
+
+	return key, value, nil
+}
+
+// WriteSnapshot writes entries to w as indented Snapshot, entries are sorted by key so that snapshots of
+// the same memories are identical
+func WriteSnapshot(w io.Writer, entries []SnapshotEntry) error {
+	sorted := make([]SnapshotEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := bytes.Compare(sorted[i].Key, sorted[j].Key); c != 0 {
+			return c < 0
+		}
+		return sorted[i].KeyType < sorted[j].KeyType
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(Snapshot{Version: SnapshotVersion, Memories: sorted}); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshot reads entries of Snapshot from r
+func ReadSnapshot(r io.Reader) ([]SnapshotEntry, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("read snapshot: unsupported version %d", s.Version)
+	}
+
+	return s.Memories, nil
+}
+
+// KeyFilter selects memories of keys, it is the filter of ExportMemory and ImportMemory of brains.
+// Keys are matched by JSON encoding, so that struct key matches the map[string]any key decoded from JSON
+func KeyFilter(keys ...any) func(key any) bool {
+	selected := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if text, ok := keyJSON(k); ok {
+			selected[text] = struct{}{}
+		}
+	}
+
+	return func(key any) bool {
+		text, ok := keyJSON(key)
+		if !ok {
+			return false
+		}
+		_, ok = selected[text]
+		return ok
+	}
+}
+
+func keyJSON(key any) (string, bool) {
+	if k, ok := key.([]byte); ok {
+		key = string(k)
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", false
+	}
+
+	return string(data), true
+}
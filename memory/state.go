@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validator is implemented by state struct which validates itself before written to memory
+type Validator interface {
+	Validate() error
+}
+
+// Schema is the memory model of state struct S, each exported field of S is one memory, the memory key is its JSON
+// tag name if any, otherwise the field name. Fields tagged `json:"-"` are not in memory.
+type Schema[S any] struct {
+	fields []stateField
+}
+
+type stateField struct {
+	name  string
+	key   string
+	index int
+	typ   reflect.Type
+}
+
+// NewSchema creates Schema of state struct S
+func NewSchema[S any]() (*Schema[S], error) {
+	t := reflect.TypeOf((*S)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("state must be a struct, got %s", t)
+	}
+
+	s := &Schema[S]{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key := strings.Split(f.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = f.Name
+		}
+		s.fields = append(s.fields, stateField{name: f.Name, key: key, index: i, typ: f.Type})
+	}
+
+	return s, nil
+}
+
+// Keys returns the memory keys of fields in field order
+func (s *Schema[S]) Keys() []string {
+	keys := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// Read reads state from memory, fields without memory are zero values
+func (s *Schema[S]) Read(r Reader) (S, error) {
+	var state S
+	v := reflect.ValueOf(&state).Elem()
+	for _, f := range s.fields {
+		if err := readField(r, f, v.Field(f.index)); err != nil {
+			var zero S
+			return zero, err
+		}
+	}
+
+	return state, nil
+}
+
+// Bind binds Schema to memory, e.g. processor.BrainContext or brain
+func (s *Schema[S]) Bind(rw ReadWriter) *State[S] {
+	return &State[S]{schema: s, rw: rw}
+}
+
+// encodeFields encodes each field to JSON, it is nil if the field can not be encoded
+func (s *Schema[S]) encodeFields(state S) [][]byte {
+	v := reflect.ValueOf(state)
+	encoded := make([][]byte, len(s.fields))
+	for i, f := range s.fields {
+		encoded[i], _ = json.Marshal(v.Field(f.index).Interface())
+	}
+	return encoded
+}
+
+func (s *Schema[S]) field(name string) (stateField, bool) {
+	for _, f := range s.fields {
+		if f.name == name || f.key == name {
+			return f, true
+		}
+	}
+	return stateField{}, false
+}
+
+// State is the typed accessor of state S in memory
+type State[S any] struct {
+	schema *Schema[S]
+	rw     ReadWriter
+}
+
+// Get reads the whole state
+func (s *State[S]) Get() (S, error) {
+	return s.schema.Read(s.rw)
+}
+
+// Set validates and writes the whole state
+func (s *State[S]) Set(state S) error {
+	if err := validate(state); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(state)
+	kvs := make([]interface{}, 0, 2*len(s.schema.fields))
+	for _, f := range s.schema.fields {
+		kvs = append(kvs, f.key, v.Field(f.index).Interface())
+	}
+
+	return s.rw.SetMemory(kvs...)
+}
+
+// Update reads the state, applies fn to it, validates and writes the changed fields only.
+// It is not atomic, concurrent updates of the same field may overwrite each other.
+func (s *State[S]) Update(fn func(state *S) error) error {
+	state, err := s.Get()
+	if err != nil {
+		return err
+	}
+	// fn may change maps and slices in place, so compare the encoded fields
+	old := s.schema.encodeFields(state)
+	if err = fn(&state); err != nil {
+		return err
+	}
+	if err = validate(state); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(state)
+	var kvs []interface{}
+	for i, encoded := range s.schema.encodeFields(state) {
+		f := s.schema.fields[i]
+		if encoded == nil || old[i] == nil || !bytes.Equal(encoded, old[i]) {
+			kvs = append(kvs, f.key, v.Field(f.index).Interface())
+		}
+	}
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	return s.rw.SetMemory(kvs...)
+}
+
+// GetField reads one field by its name or memory key
+func (s *State[S]) GetField(name string) (interface{}, error) {
+	f, ok := s.schema.field(name)
+	if !ok {
+		return nil, fmt.Errorf("state has no field %s", name)
+	}
+	v := reflect.New(f.typ).Elem()
+	if err := readField(s.rw, f, v); err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
+}
+
+// SetField validates and writes one field by its name or memory key, the value must be of the field type,
+// numbers are converted if they fit.
+func (s *State[S]) SetField(name string, value interface{}) error {
+	f, ok := s.schema.field(name)
+	if !ok {
+		return fmt.Errorf("state has no field %s", name)
+	}
+	v := reflect.New(f.typ)
+	if value != nil && !reflect.TypeOf(value).AssignableTo(f.typ) &&
+		!(isNumber(reflect.TypeOf(value).Kind()) && isNumber(f.typ.Kind())) {
+		return fmt.Errorf("set state field %s: %T is not %s", f.name, value, f.typ)
+	}
+	if err := Convert(value, v.Interface()); err != nil {
+		return fmt.Errorf("set state field %s: %w", f.name, err)
+	}
+
+	if _, isValidator := interface{}(new(S)).(Validator); isValidator {
+		return s.Update(func(state *S) error {
+			reflect.ValueOf(state).Elem().Field(f.index).Set(v.Elem())
+			return nil
+		})
+	}
+
+	return s.rw.SetMemory(f.key, v.Elem().Interface())
+}
+
+// Marshal serializes the whole state to JSON
+func (s *State[S]) Marshal() ([]byte, error) {
+	state, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(state)
+}
+
+// Unmarshal restores the whole state from JSON
+func (s *State[S]) Unmarshal(data []byte) error {
+	var state S
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("unmarshal state: %w", err)
+	}
+
+	return s.Set(state)
+}
+
+func readField(r Reader, f stateField, v reflect.Value) error {
+	target := reflect.New(f.typ)
+	if d, ok := r.(Decoder); ok {
+		found, err := d.DecodeMemory(f.key, target.Interface())
+		if err != nil {
+			return fmt.Errorf("get state field %s: %w", f.name, err)
+		}
+		if found {
+			v.Set(target.Elem())
+		}
+		return nil
+	}
+
+	if !r.ExistMemory(f.key) {
+		return nil
+	}
+	if err := Convert(r.GetMemory(f.key), target.Interface()); err != nil {
+		return fmt.Errorf("get state field %s: %w", f.name, err)
+	}
+	v.Set(target.Elem())
+
+	return nil
+}
+
+// validate validates state if S or *S implements Validator
+func validate[S any](state S) error {
+	v, ok := interface{}(&state).(Validator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+
+	return nil
+}
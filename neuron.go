@@ -44,6 +44,8 @@ type neuron struct {
 	// 触发组,触发组是用来控制 Neuron 的触发条件
 	// key: group ID, value: list of link ID
 	triggerGroups triggerGroups
+	// 通过 options 声明的触发组的汇合语义, key: group ID
+	triggerGroupSpecs map[string]core.TriggerGroupSpec
 	// 传播组,传播组是用来控制 Neuron 之间的传播关系
 	// key: group ID/Name, value: map of link ID
 	castGroups castGroups
@@ -53,12 +55,13 @@ type neuron struct {
 
 func (n *neuron) deepCopy() *neuron {
 	return &neuron{
-		id:            n.id,
-		labels:        utils.LabelsDeepCopy(n.labels),
-		processor:     n.processor,
-		triggerGroups: n.triggerGroups.deepCopy(),
-		castGroups:    n.castGroups.deepCopy(),
-		selector:      n.selector,
+		id:                n.id,
+		labels:            utils.LabelsDeepCopy(n.labels),
+		processor:         n.processor,
+		triggerGroups:     n.triggerGroups.deepCopy(),
+		triggerGroupSpecs: n.ListTriggerGroupSpecs(),
+		castGroups:        n.castGroups.deepCopy(),
+		selector:          n.selector,
 	}
 }
 
@@ -66,6 +69,7 @@ func (n *neuron) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("id", n.id).
 		Interface("labels", n.labels).
 		Interface("triggerGroups", n.triggerGroups).
+		Interface("triggerGroupSpecs", n.triggerGroupSpecs).
 		Interface("castGroups", n.castGroups.format())
 }
 
@@ -164,6 +168,15 @@ func (n *neuron) ListTriggerGroups() map[string][]string {
 	return n.triggerGroups.deepCopy()
 }
 
+func (n *neuron) ListTriggerGroupSpecs() map[string]core.TriggerGroupSpec {
+	specs := make(map[string]core.TriggerGroupSpec, len(n.triggerGroupSpecs))
+	for key, spec := range n.triggerGroupSpecs {
+		specs[key] = spec
+	}
+
+	return specs
+}
+
 func (n *neuron) ListCastGroups() map[string][]string {
 	return n.castGroups.format()
 }
@@ -179,6 +192,13 @@ func (n *neuron) SetLabels(labels map[string]string) {
 // 如果新划分的 trigger group 被存量的 trigger group 包含，那么不会创建新划分的组，
 // 因为只需要定义最大的触发条件，就会包含小的触发条件. 举例来说: 当 {A,B,C} 满足时 {A,B} 必定满足.
 func (n *neuron) AddTriggerGroup(links ...core.Link) error {
+	return n.AddTriggerGroupWithOptions(links)
+}
+
+// AddTriggerGroupWithOptions 与 AddTriggerGroup 相同, 通过 options 声明 trigger group 的汇合语义, 例如任意 K 条 link Ready 即触发,
+// 超时后以已经 Ready 的 link 触发, 或者第一条 Ready 的 link 触发并忽略其余 link.
+// 带有汇合语义的 trigger group 同样会移除被它包含的存量 trigger group, 但不会因为被存量的 trigger group 包含而不创建.
+func (n *neuron) AddTriggerGroupWithOptions(links []core.Link, opts ...core.TriggerGroupOption) error {
 	if len(links) == 0 {
 		return nil
 	}
@@ -188,23 +208,38 @@ func (n *neuron) AddTriggerGroup(links ...core.Link) error {
 		}
 	}
 
+	var spec core.TriggerGroupSpec
+	for _, opt := range opts {
+		opt.Apply(&spec)
+	}
+	isDefault := spec == core.TriggerGroupSpec{}
+
 	newGroup := make([]string, 0)
 	for _, l := range links {
 		newGroup = append(newGroup, l.GetID())
 	}
 
 	for key, group := range n.triggerGroups {
+		_, hasSpec := n.triggerGroupSpecs[key]
 		// 新划分的 trigger group 被存量的 trigger group 包含，那么不会创建新划分的组
-		if utils.SlicesContains(group, newGroup) {
+		if isDefault && !hasSpec && utils.SlicesContains(group, newGroup) {
 			return nil
 		}
 		// 新划分的 trigger group 包含了存量的 trigger group ，那存量的 trigger group 将被移除
 		if utils.SlicesContains(newGroup, group) {
 			delete(n.triggerGroups, key)
+			delete(n.triggerGroupSpecs, key)
 		}
 	}
 	// add new group
-	n.triggerGroups[utils.GenIDShort()] = newGroup
+	key := utils.GenIDShort()
+	n.triggerGroups[key] = newGroup
+	if !isDefault {
+		if n.triggerGroupSpecs == nil {
+			n.triggerGroupSpecs = make(map[string]core.TriggerGroupSpec)
+		}
+		n.triggerGroupSpecs[key] = spec
+	}
 
 	return nil
 }
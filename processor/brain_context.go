@@ -1,5 +1,7 @@
 package processor
 
+import "context"
+
 type BrainContext interface {
 	// SetMemory set memories for brain, one key value pair is one memory.
 	// memory will lazy initial util `SetMemory` or any link trig
@@ -12,18 +14,39 @@ type BrainContext interface {
 	DeleteMemory(key interface{})
 	// ClearMemory clear all memories
 	ClearMemory()
+	// ListMemoryKeys lists keys of all memories in no particular order. Keys are the original keys set, except that
+	// []byte keys are listed as string, and BrainLite decodes keys from JSON like values, e.g. structs as map[string]any
+	ListMemoryKeys() ([]interface{}, error)
+	// RangeMemory calls fn for each memory in no particular order until fn returns false. It ranges over a snapshot,
+	// so fn may read and write memory
+	RangeMemory(fn func(key, value interface{}) bool) error
+	// UpdateMemory runs fn in a memory transaction, writes of tx are applied all together if fn returns nil,
+	// and discarded if fn returns error. Other writes to memory wait until it finishes, so reads of tx see
+	// no concurrent update. Do not call memory functions of BrainContext in fn, use tx instead
+	UpdateMemory(fn func(tx MemoryTx) error) error
+	// CompareAndSwapMemory sets memory of key to new if its current value equals old, it returns whether swapped.
+	// Nil old matches the memory which does not exist. Reducer of key is not applied
+	CompareAndSwapMemory(key, old, new interface{}) (bool, error)
 	// GetCurrentNeuronID get current neuron id
 	GetCurrentNeuronID() string
 	// GetCurrentNeuronLabels get current neuron labels
 	GetCurrentNeuronLabels() map[string]string
 	// GetBrainID get brain id
-	GetBrainID() string 
+	GetBrainID() string
 	// GetBrainLabels get brain labels
 	GetBrainLabels() map[string]string
 	// ContinueCast keep current process running, and continue cast
 	ContinueCast()
-	// TODO Context 继承 context.Context
-	//context.Context
+	// SetOutput set the payload carried by out-links when current neuron casts, including ContinueCast.
+	// Payload passes data to the next neurons without shared memory keys, the last SetOutput before cast wins
+	SetOutput(payload interface{})
+	// GetInputs get payloads of in-links which triggered current activation, key is source neuron ID.
+	// The payload is nil if source neuron did not SetOutput
+	GetInputs() map[string]interface{}
+	// GetInputByLink get payload of in-link which triggered current activation by link ID
+	GetInputByLink(linkID string) interface{}
+	// Context is done when current process is canceled, e.g. brain shutdown timeout
+	context.Context
 }
 
 type BrainContextReader interface {
@@ -36,3 +59,15 @@ type BrainContextReader interface {
 	// TODO Context 继承 context.Context
 	//context.Context
 }
+
+// MemoryTx reads and writes memory in UpdateMemory, reads see the writes of tx
+type MemoryTx interface {
+	// GetMemory get memory by key
+	GetMemory(key interface{}) interface{}
+	// ExistMemory indicates whether there is a memory
+	ExistMemory(key interface{}) bool
+	// SetMemory set memories in tx, reducer of key is applied
+	SetMemory(keysAndValues ...interface{}) error
+	// DeleteMemory delete one memory by key in tx
+	DeleteMemory(key interface{})
+}
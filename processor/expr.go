@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"github.com/zenmodel/zenmodel/internal/expr"
+)
+
+// NewExprSelector creates Selector by expression evaluated over memory, the expression results in the name of cast
+// group, e.g. `len(messages[-1].tool_calls) > 0 ? "continue" : "end"`. Unlike FuncSelector, the expression is
+// a string which can be declared in config. Select returns empty cast group if evaluation fails, so nothing is cast.
+func NewExprSelector(expression string) (*ExprSelector, error) {
+	program, err := expr.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExprSelector{program: program}, nil
+}
+
+type ExprSelector struct {
+	program *expr.Program
+}
+
+func (s *ExprSelector) Select(ctx BrainContextReader) string {
+	group, err := s.program.RunString(ctx)
+	if err != nil {
+		return ""
+	}
+
+	return group
+}
+
+func (s *ExprSelector) Clone() Selector {
+	// compiled program is immutable, it can be shared
+	return &ExprSelector{program: s.program}
+}
+
+// Expression returns the source expression of selector
+func (s *ExprSelector) Expression() string {
+	return s.program.Source()
+}
+
+// NewExprCondition creates link condition by expression evaluated over memory, the expression results in bool,
+// e.g. `score >= 60 && !has("reviewed")`. The condition does not hold if evaluation fails.
+func NewExprCondition(expression string) (func(ctx BrainContextReader) bool, error) {
+	program, err := expr.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx BrainContextReader) bool {
+		ok, err := program.RunBool(ctx)
+		return err == nil && ok
+	}, nil
+}
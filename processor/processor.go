@@ -7,6 +7,12 @@ type Processor interface {
 	Clone() Processor
 }
 
+// Closer is an optional interface of Processor, Close is called when the brain shutdown.
+// A processor may be shared by brains built from the same blueprint, so Close should be reentrant.
+type Closer interface {
+	Close() error
+}
+
 func NewFuncProcessor(processFn func(ctx BrainContext) error) *FuncProcessor {
 	return &FuncProcessor{
 		processFn: processFn,
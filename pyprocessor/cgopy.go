@@ -104,6 +104,15 @@ func (p *PyProcessor) processInternal(ctx processor.BrainContext) error {
 	}
 	defer C.Py_DecRef(pyBrainContext)
 
+	// 设置当前 neuron ID, 作为 memory 的写入者
+	cNeuronIDAttr := C.CString("current_neuron_id")
+	defer C.free(unsafe.Pointer(cNeuronIDAttr))
+	cNeuronID := C.CString(ctx.GetCurrentNeuronID())
+	defer C.free(unsafe.Pointer(cNeuronID))
+	pyNeuronID := C.PyUnicode_FromString(cNeuronID)
+	C.PyObject_SetAttrString(pyBrainContext, cNeuronIDAttr, pyNeuronID)
+	C.Py_DecRef(pyNeuronID)
+
 	// 创建参数元组
 	args = C.PyTuple_New(1)
 	C.PyTuple_SetItem(args, 0, pyBrainContext)
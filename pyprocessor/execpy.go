@@ -45,7 +45,7 @@ func (p *ExecPyProcessor) Process(ctx processor.BrainContext) error {
 	}
 	defer os.Remove(p.scriptPath)
 
-	return p.execPythonScript(fmt.Sprintf("%s.db", ctx.GetBrainID()))
+	return p.execPythonScript(fmt.Sprintf("%s.db", ctx.GetBrainID()), ctx.GetCurrentNeuronID())
 }
 
 func (p *ExecPyProcessor) Clone() processor.Processor {
@@ -92,7 +92,7 @@ if __name__ == "__main__":
 	return os.WriteFile(p.scriptPath, []byte(content), 0644)
 }
 
-func (p *ExecPyProcessor) execPythonScript(sqliteDBPath string) error {
+func (p *ExecPyProcessor) execPythonScript(sqliteDBPath, neuronID string) error {
 	// 将参数转换为JSON字符串
 	paramsJSON, err := json.Marshal(p.constructorArgs)
 	if err != nil {
@@ -101,6 +101,8 @@ func (p *ExecPyProcessor) execPythonScript(sqliteDBPath string) error {
 
 	// 构造Python命令
 	cmd := exec.Command(p.pythonCmd, p.scriptPath, sqliteDBPath, string(paramsJSON))
+	// python BrainContext 通过环境变量获取当前 neuron ID, 作为 memory 的写入者
+	cmd.Env = append(os.Environ(), "ZENMODEL_NEURON_ID="+neuronID)
 
 	// 获取标准错误和标准输出管道
 	stdoutPipe, err := cmd.StdoutPipe()
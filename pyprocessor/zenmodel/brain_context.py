@@ -3,14 +3,24 @@ import json
 import sys
 import hashlib
 import os
-from typing import Any
+import math
+import base64
+import decimal
+from typing import Any, Callable, List, Optional, Tuple
 from abc import ABC
 
+# Go encoding/json 转义的字符(\b 和 \f 自 Go 1.22 起), 其他小于空格的控制字符转义为 \u00XX
+_GO_JSON_ESCAPES = {
+    '"': '\\"', '\\': '\\\\', '\n': '\\n', '\r': '\\r', '\t': '\\t', '\b': '\\b', '\f': '\\f',
+    '<': '\\u003c', '>': '\\u003e', '&': '\\u0026', '\u2028': '\\u2028', '\u2029': '\\u2029',
+}
+
 class BrainContextReader(ABC):
     def __init__(self, db_path: str):
         self.db_path = db_path
         self.conn = self.init_db()
-        self.current_neuron_id = ""
+        # neuron ID 由 Go 侧通过环境变量传入, 写入 memory 时记录为写入者
+        self.current_neuron_id = os.environ.get("ZENMODEL_NEURON_ID", "")
 
     def init_db(self):
         if not os.path.exists(self.db_path):
@@ -25,6 +35,8 @@ class BrainContextReader(ABC):
             if cursor.fetchone() is None:
                 print(f"错误：数据库文件 '{self.db_path}' 中不存在 'memory' 表", file=sys.stderr)
                 sys.exit(1)
+            cursor.execute("CREATE TABLE IF NOT EXISTS memory_writer (id INTEGER PRIMARY KEY, writer TEXT)")
+            conn.commit()
             return conn
         except sqlite3.Error as e:
             print(f"数据库连接错误: {e}", file=sys.stderr)
@@ -41,15 +53,7 @@ class BrainContextReader(ABC):
                 raise KeyError(f"未找到键 '{key}'")
             
             value_json, value_type = result
-            
-            if value_type == 'int':
-                return int(value_json)
-            elif value_type == 'float':
-                return float(value_json)
-            elif value_type == 'bool':
-                return json.loads(value_json)
-            else:
-                return json.loads(value_json)
+            return self._decode(value_json, value_type)
         except (sqlite3.Error, json.JSONDecodeError, KeyError) as e:
             print(f"获取内存错误 ({key}): {e}", file=sys.stderr)
             return None
@@ -60,20 +64,141 @@ class BrainContextReader(ABC):
         cursor.execute("SELECT 1 FROM memory WHERE key = ?", (str(hashed_key),))
         return cursor.fetchone() is not None
 
+    def list_memory_keys(self) -> List[Any]:
+        """列出所有 memory 的 key, 没有原始 key 的旧数据返回哈希后的 key"""
+        cursor = self.conn.cursor()
+        cursor.execute("SELECT key, raw_key, key_type FROM memory")
+        return [self._decode_key(hashed_key, raw_key, key_type)
+                for hashed_key, raw_key, key_type in cursor.fetchall()]
+
+    def range_memory(self, fn: Callable[[Any, Any], Optional[bool]]) -> None:
+        """对每个 memory 调用 fn(key, value), fn 返回 False 时停止"""
+        cursor = self.conn.cursor()
+        cursor.execute("SELECT key, raw_key, key_type, value, type FROM memory")
+        for hashed_key, raw_key, key_type, value_json, value_type in cursor.fetchall():
+            if fn(self._decode_key(hashed_key, raw_key, key_type), self._decode(value_json, value_type)) is False:
+                break
+
     def get_current_neuron_id(self) -> str:
         return self.current_neuron_id
 
+    @classmethod
+    def key_text(cls, key) -> str:
+        """与 Go 侧 keyText 一致: 字符串、布尔和数字按 Go 的 %v 格式化, bytes 与字符串相同,
+        其他 key 为 "json:" 加 Go encoding/json 的编码, 对象的字段按名字排序"""
+        if isinstance(key, (bytes, bytearray)):
+            return bytes(key).decode('utf-8')
+        if isinstance(key, str):
+            return key
+        if isinstance(key, bool):
+            return "true" if key else "false"
+        if isinstance(key, int):
+            return str(key)
+        if isinstance(key, float):
+            return cls._format_go_float(key)
+        return "json:" + cls._go_json(key)
+
     @staticmethod
-    def hash_key(key):
-        if not isinstance(key, (int, float, str, bytes, bytearray)):
-            raise TypeError("不支持的 key 类型")
-        
-        key_bytes = str(key).encode('utf-8')
+    def _float_digits(f: float) -> Tuple[str, int]:
+        """返回 abs(f) 最短的十进制有效数字和小数点位置, 与 Go strconv 的最短表示相同"""
+        _, digits, exponent = decimal.Decimal(repr(abs(f))).normalize().as_tuple()
+        text = "".join(str(d) for d in digits)
+        return text, len(text) + exponent
+
+    @classmethod
+    def _format_go_float(cls, f: float, json_format: bool = False) -> str:
+        """Go 的 %v (strconv 'g' 最短精度) 格式; json_format 时为 Go encoding/json 的格式"""
+        if math.isnan(f):
+            return "NaN"
+        if math.isinf(f):
+            return "+Inf" if f > 0 else "-Inf"
+        sign = "-" if math.copysign(1.0, f) < 0 else ""
+        if f == 0:
+            return sign + "0"
+        digits, point = cls._float_digits(f)
+        exp = point - 1
+        if json_format:
+            use_exp = abs(f) < 1e-6 or abs(f) >= 1e21
+        else:
+            use_exp = exp < -4 or exp >= 6
+        if use_exp:
+            mantissa = digits[0] + ("." + digits[1:] if len(digits) > 1 else "")
+            # Go 的指数至少两位, encoding/json 去掉负指数前导的 0
+            width = 1 if json_format and exp < 0 else 2
+            return sign + mantissa + "e" + ("-" if exp < 0 else "+") + str(abs(exp)).zfill(width)
+        if point <= 0:
+            return sign + "0." + "0" * -point + digits
+        if point >= len(digits):
+            return sign + digits + "0" * (point - len(digits))
+        return sign + digits[:point] + "." + digits[point:]
+
+    @classmethod
+    def _go_json(cls, value: Any) -> str:
+        """与 Go encoding/json 一致的紧凑 JSON: 非 ASCII 字符不转义, <>& 和 U+2028/U+2029 转义为 \\u 形式"""
+        if value is None:
+            return "null"
+        if isinstance(value, bool):
+            return "true" if value else "false"
+        if isinstance(value, int):
+            return str(value)
+        if isinstance(value, float):
+            if math.isnan(value) or math.isinf(value):
+                raise ValueError(f"unsupported value: {value}")
+            return cls._format_go_float(value, json_format=True)
+        if isinstance(value, (bytes, bytearray)):
+            # Go 的 []byte 编码为 base64 字符串
+            return cls._go_json(base64.b64encode(bytes(value)).decode('ascii'))
+        if isinstance(value, str):
+            return '"' + "".join(_GO_JSON_ESCAPES.get(ch) or
+                                 ("\\u%04x" % ord(ch) if ch < " " else ch) for ch in value) + '"'
+        if isinstance(value, (list, tuple)):
+            return "[" + ",".join(cls._go_json(v) for v in value) + "]"
+        if isinstance(value, dict):
+            # 与 Go 侧 canonicalJSON 一致, 字段按名字排序
+            items = sorted(((str(k), v) for k, v in value.items()), key=lambda kv: kv[0])
+            return "{" + ",".join(cls._go_json(k) + ":" + cls._go_json(v) for k, v in items) + "}"
+        raise TypeError(f"unsupported key type {type(value).__name__}")
+
+    @classmethod
+    def hash_key(cls, key):
+        key_bytes = cls.key_text(key).encode('utf-8')
         hash_bytes = hashlib.sha256(key_bytes).digest()
         hash_value = int.from_bytes(hash_bytes[:8], 'big', signed=True)
         
         return hash_value
 
+    @staticmethod
+    def _encode(value: Any) -> Tuple[str, str]:
+        """与 Go 侧 memory.EncodeValue 一致, 返回 JSON 和值的类别"""
+        if isinstance(value, str):
+            value_type = "string"
+        elif isinstance(value, bool):
+            value_type = "bool"
+        elif isinstance(value, int):
+            value_type = "int"
+        elif isinstance(value, float):
+            value_type = "float"
+        else:
+            value_type = "json"
+
+        return json.dumps(value), value_type
+
+    @staticmethod
+    def _decode(value_json: Any, value_type: str) -> Any:
+        if value_type == 'int':
+            return int(value_json)
+        elif value_type == 'float':
+            return float(value_json)
+        else:
+            # json 以及 "go:" 开头的 Go 注册类型都按 JSON 读取
+            return json.loads(value_json)
+
+    @classmethod
+    def _decode_key(cls, hashed_key: int, raw_key: Any, key_type: Optional[str]) -> Any:
+        if key_type is None:
+            return hashed_key
+        return cls._decode(raw_key, key_type)
+
 class BrainContext(BrainContextReader):
     def __init__(self, db_path: str):
         super().__init__(db_path)
@@ -89,22 +214,14 @@ class BrainContext(BrainContextReader):
     def _set_single_memory(self, key: Any, value: Any) -> None:
         try:
             cursor = self.conn.cursor()
-            if isinstance(value, str):
-                value_type = "string"
-            elif isinstance(value, bool):
-                value_type = "bool"
-            elif isinstance(value, int):
-                value_type = "int"
-            elif isinstance(value, float):
-                value_type = "float"
-            else:
-                value_type = "json"
-            
-            value_json = json.dumps(value)
+            value_json, value_type = self._encode(value)
+            # bytes key 与字符串是同一个 key
+            raw_key, key_type = self._encode(bytes(key).decode('utf-8') if isinstance(key, (bytes, bytearray)) else key)
             
             hashed_key = self.hash_key(key)
-            cursor.execute("INSERT OR REPLACE INTO memory (key, value, type) VALUES (?, ?, ?)",
-                           (str(hashed_key), value_json, value_type))
+            self._set_writer(cursor)
+            cursor.execute("INSERT OR REPLACE INTO memory (key, value, type, raw_key, key_type) VALUES (?, ?, ?, ?, ?)",
+                           (str(hashed_key), value_json, value_type, raw_key, key_type))
             self.conn.commit()
         except (sqlite3.Error, json.JSONDecodeError) as e:
             print(f"设置内存错误 ({key}): {e}", file=sys.stderr)
@@ -112,14 +229,21 @@ class BrainContext(BrainContextReader):
     def delete_memory(self, key: Any) -> None:
         cursor = self.conn.cursor()
         hashed_key = self.hash_key(key)
+        self._set_writer(cursor)
         cursor.execute("DELETE FROM memory WHERE key = ?", (str(hashed_key),))
         self.conn.commit()
 
     def clear_memory(self) -> None:
         cursor = self.conn.cursor()
+        self._set_writer(cursor)
         cursor.execute("DELETE FROM memory")
         self.conn.commit()
 
+    def _set_writer(self, cursor) -> None:
+        # 与写入在同一事务中, memory 的 changelog 触发器读取它作为写入者
+        cursor.execute("INSERT OR REPLACE INTO memory_writer (id, writer) VALUES (1, ?)",
+                       (self.current_neuron_id,))
+
     def continue_cast(self) -> None:
         # 这里需要实现继续处理的逻辑
         pass
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestStreamProducer(t *testing.T) {
+	const n = 5
+	var consumed int32
+	bp := zenmodel.NewBlueprint()
+	producer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		for i := 0; i < n; i++ {
+			bc.ContinueCast()
+		}
+		return nil
+	}, core.WithStreamProducer())
+	consumer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(&consumed, 1)
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	entry, _ := bp.AddEntryLinkTo(producer)
+	_, _ = bp.AddLink(producer, consumer)
+
+	brain := brainlite.BuildBrain(bp)
+	_ = brain.TrigLinks(entry)
+	waitWithTimeout(t, brain, 10*time.Second)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	if got := atomic.LoadInt32(&consumed); got != n {
+		t.Fatalf("expect consumer processed %d times, got %d", n, got)
+	}
+}
+
+func TestCastRetryOverflow(t *testing.T) {
+	cases := []struct {
+		overflow core.CastOverflow
+		expect   int32
+	}{
+		{overflow: core.CastOverflowDrop, expect: 1},
+		{overflow: core.CastOverflowQueue, expect: 3},
+	}
+	for _, c := range cases {
+		t.Run(string(c.overflow), func(t *testing.T) {
+			var consumed int32
+			bp := zenmodel.NewBlueprint()
+			producer := bp.AddNeuron(func(bc processor.BrainContext) error {
+				for i := 0; i < 3; i++ {
+					bc.ContinueCast()
+				}
+				return nil
+			}, core.WithCastRetryPolicy(core.CastRetryPolicy{
+				Delay:       10 * time.Millisecond,
+				Multiplier:  2,
+				MaxAttempts: 1,
+				Overflow:    c.overflow,
+			}))
+			consumer := bp.AddNeuron(func(bc processor.BrainContext) error {
+				atomic.AddInt32(&consumed, 1)
+				time.Sleep(300 * time.Millisecond)
+				return nil
+			})
+			entry, _ := bp.AddEntryLinkTo(producer)
+			_, _ = bp.AddLink(producer, consumer)
+
+			brain := brainlite.BuildBrain(bp)
+			_ = brain.TrigLinks(entry)
+			waitWithTimeout(t, brain, 10*time.Second)
+			defer func() { _ = brain.Shutdown(context.Background()) }()
+
+			if got := atomic.LoadInt32(&consumed); got != c.expect {
+				t.Fatalf("expect consumer processed %d times, got %d", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestCastRetryBackoff(t *testing.T) {
+	p := core.CastRetryPolicy{Delay: 100 * time.Millisecond, Multiplier: 2}
+	for attempt, expect := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+	} {
+		if got := p.Backoff(attempt); got != expect {
+			t.Errorf("attempt %d: expect %s, got %s", attempt, expect, got)
+		}
+	}
+}
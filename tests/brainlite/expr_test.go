@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type exprMessage struct {
+	Role      string   `json:"role"`
+	Content   string   `json:"content"`
+	ToolCalls []string `json:"tool_calls,omitempty"`
+}
+
+// memoryReader is a BrainContextReader over map
+type memoryReader map[string]interface{}
+
+func (r memoryReader) GetMemory(key interface{}) interface{} {
+	return r[key.(string)]
+}
+
+func (r memoryReader) ExistMemory(key interface{}) bool {
+	_, ok := r[key.(string)]
+	return ok
+}
+
+func (r memoryReader) GetCurrentNeuronID() string {
+	return ""
+}
+
+func TestExprSelector(t *testing.T) {
+	memory := memoryReader{
+		"messages": []exprMessage{
+			{Role: "user", Content: "weather?"},
+			{Role: "assistant", ToolCalls: []string{"get_weather"}},
+		},
+		"score":  float64(75), // number read from JSON memory
+		"tags":   []interface{}{"urgent", "billing"},
+		"config": map[string]interface{}{"mode": "Strict", "retries": 2},
+		// keys which are keywords or not identifiers
+		"in":        "keyword",
+		"user-name": "bob",
+		"true":      false,
+	}
+	cases := []struct {
+		expression string
+		expect     string
+	}{
+		{expression: `len(messages[-1].tool_calls) > 0 ? "continue" : "end"`, expect: "continue"},
+		{expression: `len(messages[0].ToolCalls) > 0 ? "continue" : "end"`, expect: "end"},
+		{expression: `messages[-1]["role"] == "assistant" ? "assistant" : "user"`, expect: "assistant"},
+		{expression: `score >= 60 && score < 80 ? "pass" : "other"`, expect: "pass"},
+		{expression: `score == 75 ? "int equals float" : "not equal"`, expect: "int equals float"},
+		{expression: `"urgent" in tags ? "escalate" : "queue"`, expect: "escalate"},
+		{expression: `lower(config.mode) + "-" + (config.retries * 2 + 1 > 4 ? "retry" : "once")`, expect: "strict-retry"},
+		{expression: `has("missing") || missing.field != nil ? "found" : "default"`, expect: "default"},
+		{expression: `len(missing) == 0 && messages[10] == nil ? 'empty' : "other"`, expect: "empty"},
+		{expression: `!(score > 100) ? 'in range' : "out of range"`, expect: "in range"},
+		{expression: `'say "hi"'`, expect: `say "hi"`},
+		{expression: `'\"quoted\"' + ' it\'s'`, expect: `"quoted" it's`},
+		{expression: `"it's" + ' \\'`, expect: `it's \`},
+		{expression: "`in` + \"-\" + `user-name`", expect: "keyword-bob"},
+		{expression: "`true` ? \"true\" : \"false\"", expect: "false"},
+		{expression: "\"keyword\" in [`in`] ? 'in' : 'not in'", expect: "in"},
+		{expression: "config.`mode`", expect: "Strict"},
+		{expression: `score + 1`, expect: ""}, // not string, nothing selected
+	}
+	for _, c := range cases {
+		selector, err := processor.NewExprSelector(c.expression)
+		if err != nil {
+			t.Fatalf("compile %s: %v", c.expression, err)
+		}
+		if got := selector.Select(memory); got != c.expect {
+			t.Errorf("expect %s selects %q, got %q", c.expression, c.expect, got)
+		}
+		if got := selector.Clone().Select(memory); got != c.expect {
+			t.Errorf("expect clone of %s selects %q, got %q", c.expression, c.expect, got)
+		}
+	}
+
+	for _, invalid := range []string{`score >`, `"unterminated`, `unknown(score)`, `score ? "a"`, `messages[0`, `score # 1`, "`in", "``"} {
+		if _, err := processor.NewExprSelector(invalid); err == nil {
+			t.Errorf("expect compile error for %s", invalid)
+		}
+	}
+}
+
+func TestExprCondition(t *testing.T) {
+	memory := memoryReader{"score": 90, "reviewed": true}
+	cases := map[string]bool{
+		`score >= 60 && !has("reviewed")`: false,
+		`score >= 60 && reviewed`:         true,
+		`score / 0 > 1`:                   false, // evaluation error, condition does not hold
+		`score`:                           false, // not bool
+	}
+	for expression, expect := range cases {
+		condition, err := processor.NewExprCondition(expression)
+		if err != nil {
+			t.Fatalf("compile %s: %v", expression, err)
+		}
+		if got := condition(memory); got != expect {
+			t.Errorf("expect %s is %v, got %v", expression, expect, got)
+		}
+	}
+}
+
+func TestExprRouting(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	agent := bp.AddNeuron(func(bc processor.BrainContext) error {
+		turn := asInt(bc.GetMemory("turn")) + 1
+		messages := []exprMessage{{Role: "user", Content: "weather?"}}
+		if turn == 1 {
+			messages = append(messages, exprMessage{Role: "assistant", ToolCalls: []string{"get_weather"}})
+		} else {
+			messages = append(messages, exprMessage{Role: "assistant", Content: "sunny"})
+		}
+		return bc.SetMemory("turn", turn, "messages", messages)
+	}, core.MustSelectorExpr(`len(messages[-1].tool_calls) > 0 ? "continue" : "end"`))
+	tool := bp.AddNeuron(nopProcess)
+	agentToTool, _ := bp.AddLink(agent, tool)
+	toolToAgent, _ := bp.AddLink(tool, agent, core.MustConditionExpr(`turn < 5`))
+	agentToEnd, _ := bp.AddEndLinkFrom(agent, core.WithOutcome("answered"))
+	_, _ = bp.AddEntryLinkTo(agent)
+	_ = agent.AddCastGroup("continue", agentToTool)
+	_ = agent.AddCastGroup("end", agentToEnd)
+
+	if toolToAgent.GetLabels()[core.LinkLabelConditionExpr] != `turn < 5` ||
+		agent.GetLabels()[core.NeuronLabelSelectorExpr] == "" {
+		t.Fatal("expect expressions recorded in labels")
+	}
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("turn", 0)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if turn := asInt(brain.GetMemory("turn")); turn != 2 {
+		t.Fatalf("expect agent runs 2 turns, got %d", turn)
+	}
+	if outcome := brain.GetOutcome(); outcome != "answered" {
+		t.Fatalf("expect outcome answered, got %q", outcome)
+	}
+}
+
+func TestInvalidExpr(t *testing.T) {
+	if opt, err := core.WithConditionExpr(`score >=`); err == nil || opt != nil {
+		t.Fatalf("expect error of invalid condition expression, got %v", opt)
+	}
+	if opt, err := core.WithSelectorExpr(`messages[`); err == nil || opt != nil {
+		t.Fatalf("expect error of invalid selector expression, got %v", opt)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect MustConditionExpr panics on invalid expression")
+		}
+	}()
+	core.MustConditionExpr(`score >=`)
+}
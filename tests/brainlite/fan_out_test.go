@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+const fanOutWidth = 500
+
+func TestWideFanOut(t *testing.T) {
+	var leafCnt int32
+	bp := zenmodel.NewBlueprint()
+	src := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return nil
+	})
+	for i := 0; i < fanOutWidth; i++ {
+		leaf := bp.AddNeuron(func(bc processor.BrainContext) error {
+			atomic.AddInt32(&leafCnt, 1)
+			return nil
+		})
+		_, _ = bp.AddLink(src, leaf)
+	}
+	_, _ = bp.AddEntryLinkTo(src)
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronQueueLen(1))
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 30*time.Second)
+
+	if got := atomic.LoadInt32(&leafCnt); got != fanOutWidth {
+		t.Fatalf("expect %d leaf neurons processed, got %d", fanOutWidth, got)
+	}
+	_ = brain.Shutdown(context.Background())
+}
+
+func TestWideFanOutAndJoin(t *testing.T) {
+	var leafCnt, joinCnt int32
+	bp := zenmodel.NewBlueprint()
+	src := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return nil
+	})
+	join := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(&joinCnt, 1)
+		return nil
+	})
+	joinLinks := make([]core.Link, 0, fanOutWidth)
+	for i := 0; i < fanOutWidth; i++ {
+		leaf := bp.AddNeuron(func(bc processor.BrainContext) error {
+			atomic.AddInt32(&leafCnt, 1)
+			return nil
+		})
+		_, _ = bp.AddLink(src, leaf)
+		l, _ := bp.AddLink(leaf, join)
+		joinLinks = append(joinLinks, l)
+	}
+	_ = join.AddTriggerGroup(joinLinks...)
+	_, _ = bp.AddEntryLinkTo(src)
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronWorkerNum(16))
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 30*time.Second)
+
+	if got := atomic.LoadInt32(&leafCnt); got != fanOutWidth {
+		t.Fatalf("expect %d leaf neurons processed, got %d", fanOutWidth, got)
+	}
+	if got := atomic.LoadInt32(&joinCnt); got != 1 {
+		t.Fatalf("expect join neuron processed once, got %d", got)
+	}
+	_ = brain.Shutdown(context.Background())
+}
+
+func waitWithTimeout(t *testing.T, brain core.Brain, timeout time.Duration) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		brain.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("brain is still %s after %s, maybe deadlock", brain.GetState(), timeout)
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+)
+
+func hashMemoryKey(text string) int64 {
+	h := sha256.Sum256([]byte(text))
+	return int64(binary.BigEndian.Uint64(h[:8]))
+}
+
+// TestListMemoryKeysExternalWrite writes the SQLite file as Python processors do, with and without original keys
+func TestListMemoryKeysExternalWrite(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("keys-external"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("go", 1)
+
+	db, err := sql.Open("sqlite3", "keys-external.db")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec("INSERT INTO memory (key, value, type, raw_key, key_type) VALUES (?, ?, ?, ?, ?)",
+		hashMemoryKey(`json:{"Name":"py"}`), "2", "int", `{"Name":"py"}`, "json")
+	if err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	// old Python processors write no original key
+	if _, err = db.Exec("INSERT INTO memory (key, value, type) VALUES (?, ?, ?)", hashMemoryKey("old"), "3", "int"); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+
+	keys, err := brain.ListMemoryKeys()
+	if err != nil {
+		t.Fatalf("list memory keys failed: %v", err)
+	}
+	got := make([]string, 0, len(keys))
+	for _, k := range keys {
+		got = append(got, fmt.Sprint(k))
+	}
+	sort.Strings(got)
+	expect := []string{fmt.Sprint(hashMemoryKey("old")), "go", "map[Name:py]"}
+	sort.Strings(expect)
+	if fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Fatalf("expect keys %v, got %v", expect, got)
+	}
+	if v := asInt(brain.GetMemory(memoryKey{Name: "py"})); v != 2 {
+		t.Fatalf("expect memory of struct key 2, got %d", v)
+	}
+}
+
+// TestListMemoryKeysLegacyFile opens memory file created without original key columns
+func TestListMemoryKeysLegacyFile(t *testing.T) {
+	db, err := sql.Open("sqlite3", "keys-legacy.db")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	_, err = db.Exec("CREATE TABLE memory (key INTEGER PRIMARY KEY, value JSON, type TEXT)")
+	if err == nil {
+		_, err = db.Exec("INSERT INTO memory (key, value, type) VALUES (?, ?, ?)", hashMemoryKey("old"), "1", "int")
+	}
+	_ = db.Close()
+	if err != nil {
+		t.Fatalf("create legacy memory failed: %v", err)
+	}
+
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("keys-legacy"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	if err = brain.SetMemory("new", 2); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if v := asInt(brain.GetMemory("old")); v != 1 {
+		t.Fatalf("expect legacy memory 1, got %d", v)
+	}
+	keys, err := brain.ListMemoryKeys()
+	if err != nil {
+		t.Fatalf("list memory keys failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expect 2 keys, got %v", keys)
+	}
+}
+
+// TestImportMemoryUnregisteredKey imports struct keys which are decoded as map[string]any, they are the same keys as
+// the structs whatever the order of fields in snapshot
+func TestImportMemoryUnregisteredKey(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory(listedKey{ID: 1, Group: "x"}, 1)
+
+	var buf bytes.Buffer
+	if err := brain.ExportMemory(&buf, nil); err != nil {
+		t.Fatalf("export memory failed: %v", err)
+	}
+	brain.ClearMemory()
+	if err := brain.ImportMemory(&buf, nil); err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+	if got := asInt(brain.GetMemory(listedKey{ID: 1, Group: "x"})); got != 1 {
+		t.Fatalf("expect imported memory 1, got %d", got)
+	}
+
+	// snapshot written by hand, fields of key are in declaration order
+	snapshot := `{"version": 1, "memories": [
+		{"key": {"ID": 2, "Group": "y"}, "key_type": "json", "value": 2, "value_type": "int"}]}`
+	if err := brain.ImportMemory(strings.NewReader(snapshot), nil); err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+	if got := asInt(brain.GetMemory(listedKey{ID: 2, Group: "y"})); got != 2 || !brain.ExistMemory(listedKey{ID: 2, Group: "y"}) {
+		t.Fatalf("expect imported memory 2, got %d", got)
+	}
+	brain.DeleteMemory(listedKey{ID: 2, Group: "y"})
+	if keys, _ := brain.ListMemoryKeys(); len(keys) != 1 {
+		t.Fatalf("expect imported struct key deleted, got %v", keys)
+	}
+}
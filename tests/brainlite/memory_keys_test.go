@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type memoryKey struct {
+	Name string
+}
+
+func TestListMemoryKeys(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("a", 1, []byte("b"), 2, 3, 3, memoryKey{Name: "x"}, 4)
+
+	keys, err := brain.ListMemoryKeys()
+	if err != nil {
+		t.Fatalf("list memory keys failed: %v", err)
+	}
+	if len(keys) != 4 {
+		t.Fatalf("expect 4 keys, got %v", keys)
+	}
+	// listed keys read their memories, []byte key is listed as string
+	var sum int
+	var names []string
+	for _, k := range keys {
+		sum += asInt(brain.GetMemory(k))
+		if name, ok := k.(string); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if sum != 10 || fmt.Sprint(names) != "[a b]" {
+		t.Fatalf("expect memories of keys sum 10 with string keys [a b], got %d and %v", sum, names)
+	}
+	if got := asInt(brain.GetMemory(memoryKey{Name: "x"})); got != 4 {
+		t.Fatalf("expect struct key memory 4, got %d", got)
+	}
+}
+
+func TestRangeMemory(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("a", 1, "b", 2, "c", 3)
+
+	var calls int
+	_ = brain.RangeMemory(func(key, value any) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Fatalf("expect range stops after 1 call, got %d", calls)
+	}
+
+	// fn may write memory while ranging
+	err := brain.RangeMemory(func(key, value any) bool {
+		_ = brain.SetMemory(fmt.Sprintf("copy-%v", key), value)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("range memory failed: %v", err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if brain.GetMemory("copy-"+k) == nil {
+			t.Fatalf("expect copy of %s", k)
+		}
+	}
+}
+
+func TestRangeMemoryInMapItem(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	mapper := bp.AddNeuron(func(bc processor.BrainContext) error {
+		_ = bc.SetMemory("factor", 0)
+		keys, err := bc.ListMemoryKeys()
+		if err != nil {
+			return err
+		}
+		var factors int
+		err = bc.RangeMemory(func(key, value any) bool {
+			if key == "factor" {
+				factors++
+				if asInt(value) != 0 {
+					factors += 100
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		// item, index, scoped factor and brain items
+		return bc.SetMemory(core.MapResultMemoryKey, []int{len(keys), factors})
+	}, core.WithMap("items", "results"))
+	_, _ = bp.AddEntryLinkTo(mapper)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("items", []int{1}, "factor", 10)
+	waitWithTimeout(t, brain, 5*time.Second)
+
+	results, _ := brain.GetMemory("results").([]any)
+	if len(results) != 1 || fmt.Sprint(results[0]) != "[4 1]" {
+		t.Fatalf("expect 4 keys with scoped factor only, got %v", results)
+	}
+}
+
+// listedKey is not registered by memory.RegisterType, BrainLite lists it as map[string]any.
+// Its fields are not in alphabetical order, while fields of listed map are
+type listedKey struct {
+	ID    int
+	Group string
+}
+
+// TestListedMemoryKeysRoundTrip writes memories by the listed keys
+func TestListedMemoryKeysRoundTrip(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("a", 1, listedKey{ID: 1, Group: "x"}, 1, listedKey{ID: 2, Group: "y"}, 1)
+
+	keys, err := brain.ListMemoryKeys()
+	if err != nil {
+		t.Fatalf("list memory keys failed: %v", err)
+	}
+	for _, k := range keys {
+		if !brain.ExistMemory(k) || asInt(brain.GetMemory(k)) != 1 {
+			t.Fatalf("expect memory 1 of listed key %v", k)
+		}
+		if err = brain.SetMemory(k, 2); err != nil {
+			t.Fatalf("set memory of listed key %v failed: %v", k, err)
+		}
+		if swapped, err := brain.CompareAndSwapMemory(k, 2, 3); err != nil || !swapped {
+			t.Fatalf("expect memory of listed key %v swapped, got %v, %v", k, swapped, err)
+		}
+	}
+	if got := asInt(brain.GetMemory(listedKey{ID: 2, Group: "y"})); got != 3 {
+		t.Fatalf("expect struct key memory 3, got %d", got)
+	}
+	if keys, _ = brain.ListMemoryKeys(); len(keys) != 3 {
+		t.Fatalf("expect listed keys are the same keys, got %v", keys)
+	}
+
+	for _, k := range keys {
+		brain.DeleteMemory(k)
+	}
+	if keys, _ = brain.ListMemoryKeys(); len(keys) != 0 {
+		t.Fatalf("expect memories deleted by listed keys, got %v", keys)
+	}
+}
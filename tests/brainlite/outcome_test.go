@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestEndOutcome(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	answer := bp.AddNeuron(nopProcess)
+	answered, _ := bp.AddEndLinkFrom(answer, core.WithOutcome("answered"))
+	gaveUp, _ := bp.AddEndLinkFrom(answer, core.WithOutcome("gave_up"))
+	_, _ = bp.AddEntryLinkTo(answer)
+	_ = answer.AddCastGroup("answered", answered)
+	_ = answer.AddCastGroup("gave_up", gaveUp)
+	answer.BindCastGroupSelectFunc(func(bcr processor.BrainContextReader) string {
+		if bcr.ExistMemory("question") {
+			return "answered"
+		}
+		return "gave_up"
+	})
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	_ = brain.EntryWithMemory("question", "what is zenmodel?")
+	waitWithTimeout(t, brain, 10*time.Second)
+	if outcome := brain.GetOutcome(); outcome != "answered" {
+		t.Fatalf("expect outcome answered, got %q", outcome)
+	}
+	if result := brain.GetRunResult(); result.Outcome != "answered" || result.Err != nil {
+		t.Fatalf("expect run result with outcome answered, got %+v", result)
+	}
+
+	brain.ClearMemory()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+	if outcome := brain.GetOutcome(); outcome != "gave_up" {
+		t.Fatalf("expect outcome gave_up, got %q", outcome)
+	}
+}
+
+func TestEndOutcomeEmpty(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	a := bp.AddNeuron(nopProcess)
+	_, _ = bp.AddEntryLinkTo(a)
+	_, _ = bp.AddEndLinkFrom(a)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+	if outcome := brain.GetOutcome(); outcome != "" {
+		t.Fatalf("expect empty outcome, got %q", outcome)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -39,7 +40,7 @@ func TestParallelAndWait(t *testing.T) {
 	_ = brain.TrigLinks(entryInput)
 	brain.Wait()
 
-	brain.Shutdown()
+	_ = brain.Shutdown(context.Background())
 }
 
 func inputFn(b processor.BrainContext) error {
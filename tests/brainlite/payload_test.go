@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestLinkPayload(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inputs   map[string]interface{}
+		byLink   interface{}
+		wantLink string
+	)
+	bp := zenmodel.NewBlueprint()
+	// parallel branches use the same output name without colliding in memory
+	weather := bp.AddNeuron(func(bc processor.BrainContext) error {
+		bc.SetOutput(map[string]string{"template": "sunny"})
+		return nil
+	})
+	news := bp.AddNeuron(func(bc processor.BrainContext) error {
+		bc.SetOutput(map[string]string{"template": "headlines"})
+		return nil
+	})
+	summary := bp.AddNeuron(func(bc processor.BrainContext) error {
+		mu.Lock()
+		defer mu.Unlock()
+		inputs = bc.GetInputs()
+		byLink = bc.GetInputByLink(wantLink)
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(weather)
+	_, _ = bp.AddEntryLinkTo(news)
+	weatherToSummary, _ := bp.AddLink(weather, summary)
+	newsToSummary, _ := bp.AddLink(news, summary)
+	_ = summary.AddTriggerGroup(weatherToSummary, newsToSummary)
+	wantLink = weatherToSummary.GetID()
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expect := map[string]interface{}{
+		weather.GetID(): map[string]string{"template": "sunny"},
+		news.GetID():    map[string]string{"template": "headlines"},
+	}
+	if !reflect.DeepEqual(inputs, expect) {
+		t.Fatalf("expect inputs %v, got %v", expect, inputs)
+	}
+	if !reflect.DeepEqual(byLink, map[string]string{"template": "sunny"}) {
+		t.Fatalf("expect input of link %s is weather output, got %v", wantLink, byLink)
+	}
+	if brain.ExistMemory("template") {
+		t.Fatal("expect payloads do not touch memory")
+	}
+}
+
+func TestLinkPayloadStream(t *testing.T) {
+	const n = 5
+	var (
+		mu       sync.Mutex
+		received []interface{}
+	)
+	bp := zenmodel.NewBlueprint()
+	producer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		for i := 0; i < n; i++ {
+			bc.SetOutput(i)
+			bc.ContinueCast()
+		}
+		return nil
+	}, core.WithStreamProducer())
+	consumer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		mu.Lock()
+		received = append(received, bc.GetInputs()[producer.GetID()])
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(producer)
+	_, _ = bp.AddLink(producer, consumer)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expect := []interface{}{0, 1, 2, 3, 4}
+	if !reflect.DeepEqual(received, expect) {
+		t.Fatalf("expect payloads %v in order, got %v", expect, received)
+	}
+}
+
+func TestMapPayload(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		results interface{}
+	)
+	bp := zenmodel.NewBlueprint()
+	square := bp.AddNeuron(func(bc processor.BrainContext) error {
+		v := asInt(bc.GetMemory(core.MapItemMemoryKey))
+		bc.SetOutput(v * v)
+		return nil
+	}, core.WithMap("numbers", "squares"))
+	reduce := bp.AddNeuron(func(bc processor.BrainContext) error {
+		mu.Lock()
+		defer mu.Unlock()
+		results = bc.GetInputs()[square.GetID()]
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(square)
+	_, _ = bp.AddLink(square, reduce)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("numbers", []int{1, 2, 3})
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expect := []any{1, 4, 9}
+	if !reflect.DeepEqual(results, expect) {
+		t.Fatalf("expect map results %v as payload, got %v", expect, results)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+)
+
+type floatKey struct {
+	X float64
+	Y float64
+}
+
+// pinnedKeys are keys with their pinned text hashed in SQLite, py is the same key as Python literal
+var pinnedKeys = []struct {
+	key  any
+	py   string
+	text string
+}{
+	{key: 1234567.0, py: `1234567.0`, text: `1.234567e+06`},
+	{key: 1e20, py: `1e20`, text: `1e+20`},
+	{key: 123456789.5, py: `123456789.5`, text: `1.234567895e+08`},
+	{key: 0.00001, py: `0.00001`, text: `1e-05`},
+	{key: -0.25, py: `-0.25`, text: `-0.25`},
+	{key: "你好", py: `"你好"`, text: `你好`},
+	{key: memoryKey{Name: "你好 <a&b>"}, py: `{"Name": "你好 <a&b>"}`, text: `json:{"Name":"你好 \u003ca\u0026b\u003e"}`},
+	{key: floatKey{X: 1, Y: 1e-7}, py: `{"X": 1.0, "Y": 1e-07}`, text: `json:{"X":1,"Y":1e-7}`},
+	{key: []any{"é", 1e21}, py: `["é", 1e21]`, text: `json:["é",1e+21]`},
+	{key: listedKey{ID: 1, Group: "x"}, py: `{"ID": 1, "Group": "x"}`, text: `json:{"Group":"x","ID":1}`},
+	{key: []any{map[string]any{"b": 1, "a": []any{}}}, py: `[{"b": 1, "a": []}]`, text: `json:[{"a":[],"b":1}]`},
+}
+
+// TestPythonKeyText checks that Go and Python processors hash keys by the same text
+func TestPythonKeyText(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("python-keys"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	pyKeys := make([]string, 0, len(pinnedKeys))
+	for i, k := range pinnedKeys {
+		if err = brain.SetMemory(k.key, i); err != nil {
+			t.Fatalf("set memory of %v failed: %v", k.key, err)
+		}
+		pyKeys = append(pyKeys, k.py)
+	}
+
+	db, err := sql.Open("sqlite3", "python-keys.db")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	defer db.Close()
+	for i, k := range pinnedKeys {
+		var value int
+		if err = db.QueryRow("SELECT value FROM memory WHERE key = ?", hashMemoryKey(k.text)).Scan(&value); err != nil || value != i {
+			t.Fatalf("expect memory of %v hashed by text %s, got %d, %v", k.key, k.text, value, err)
+		}
+	}
+
+	pyprocessor, err := filepath.Abs("../../pyprocessor")
+	if err != nil {
+		t.Fatalf("find pyprocessor failed: %v", err)
+	}
+	script := "import sys, json\n" +
+		"sys.path.insert(0, sys.argv[1])\n" +
+		"from zenmodel import BrainContext\n" +
+		"keys = [" + strings.Join(pyKeys, ", ") + "]\n" +
+		"print(json.dumps([[BrainContext.key_text(k), str(BrainContext.hash_key(k))] for k in keys]))\n"
+	out, err := exec.Command(python, "-c", script, pyprocessor).Output()
+	if err != nil {
+		t.Fatalf("run python failed: %v", err)
+	}
+	var got [][2]string
+	if err = json.Unmarshal(out, &got); err != nil || len(got) != len(pinnedKeys) {
+		t.Fatalf("unexpected python output %s: %v", out, err)
+	}
+	for i, k := range pinnedKeys {
+		if got[i][0] != k.text {
+			t.Errorf("expect python key text of %s is %s, got %s", k.py, k.text, got[i][0])
+		}
+		if hash := strconv.FormatInt(hashMemoryKey(k.text), 10); got[i][1] != hash {
+			t.Errorf("expect python hash of %s is %s, got %s", k.py, hash, got[i][1])
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+)
+
+type chatMessage struct {
+	Role    string
+	Content string
+	Tokens  int64
+}
+
+type point struct {
+	X, Y float64
+}
+
+func init() {
+	brainlite.RegisterType([]chatMessage{})
+	brainlite.RegisterType(&chatMessage{})
+	brainlite.RegisterTypeName("tests.point", point{})
+}
+
+func TestRegisteredType(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("messages", core.AppendReducer)
+	brain := brainlite.BuildBrain(bp, brainlite.WithID("registered-type"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	messages := []chatMessage{{Role: "user", Content: "hi", Tokens: 1 << 40}}
+	last := &chatMessage{Role: "assistant", Content: "hello"}
+	_ = brain.SetMemory("messages", messages, "last", last, "point", point{X: 1, Y: 2}, "raw", chatMessage{Role: "user"})
+
+	if got, ok := brain.GetMemory("messages").([]chatMessage); !ok || !reflect.DeepEqual(got, messages) {
+		t.Fatalf("expect messages %v, got %#v", messages, brain.GetMemory("messages"))
+	}
+	if got, ok := brain.GetMemory("last").(*chatMessage); !ok || !reflect.DeepEqual(got, last) {
+		t.Fatalf("expect last %v, got %#v", last, brain.GetMemory("last"))
+	}
+	if got, ok := brain.GetMemory("point").(point); !ok || got != (point{X: 1, Y: 2}) {
+		t.Fatalf("expect point, got %#v", brain.GetMemory("point"))
+	}
+	// unregistered type is read as JSON
+	if _, ok := brain.GetMemory("raw").(map[string]any); !ok {
+		t.Fatalf("expect unregistered type read as map, got %#v", brain.GetMemory("raw"))
+	}
+
+	// reducer reads the registered type
+	_ = brain.SetMemory("messages", *last)
+	if got, ok := brain.GetMemory("messages").([]chatMessage); !ok || len(got) != 2 || got[1] != *last {
+		t.Fatalf("expect appended messages, got %#v", brain.GetMemory("messages"))
+	}
+}
+
+// TestRegisteredTypeReadableAsJSON reads the SQLite file as Python processors do
+func TestRegisteredTypeReadableAsJSON(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("registered-type-json"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("point", point{X: 1, Y: 2})
+
+	db, err := sql.Open("sqlite3", "registered-type-json.db")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	defer db.Close()
+	var value, valueType string
+	if err = db.QueryRow("SELECT value, type FROM memory WHERE key = ?", hashMemoryKey("point")).Scan(&value, &valueType); err != nil {
+		t.Fatalf("query memory failed: %v", err)
+	}
+	var decoded map[string]any
+	if err = json.Unmarshal([]byte(value), &decoded); err != nil || decoded["X"] != 1.0 {
+		t.Fatalf("expect point readable as JSON, got %s: %v", value, err)
+	}
+	if !strings.HasPrefix(valueType, "go:") {
+		t.Fatalf("expect type tagged with registered name, got %s", valueType)
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestRetriggerIgnoreAndQueue(t *testing.T) {
+	cases := []struct {
+		policy  core.RetriggerPolicy
+		process int
+	}{
+		{policy: core.RetriggerIgnore, process: 1},
+		{policy: core.RetriggerQueue, process: 2},
+	}
+	for _, c := range cases {
+		t.Run(string(c.policy), func(t *testing.T) {
+			var calls int32
+			started, release := make(chan struct{}), make(chan struct{})
+			bp := zenmodel.NewBlueprint()
+			neu := bp.AddNeuron(func(bc processor.BrainContext) error {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					close(started)
+					<-release
+				}
+				return nil
+			}, core.WithRetriggerPolicy(c.policy))
+			entry, _ := bp.AddEntryLinkTo(neu)
+
+			brain := brainlite.BuildBrain(bp)
+			defer func() { _ = brain.Shutdown(context.Background()) }()
+			_ = brain.TrigLinks(entry)
+			<-started
+			_ = brain.TrigLinks(entry)
+			close(release)
+			waitWithTimeout(t, brain, 10*time.Second)
+
+			if n := brain.Status().Neurons[neu.GetID()]; n.Process != c.process || n.Succeed != c.process {
+				t.Fatalf("expect neuron processed %d times, got %+v", c.process, n)
+			}
+		})
+	}
+}
+
+func TestRetriggerRestart(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	bp := zenmodel.NewBlueprint()
+	neu := bp.AddNeuron(func(bc processor.BrainContext) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-bc.Done()
+			canceled <- bc.Err()
+			return bc.Err()
+		}
+		return nil
+	}, core.WithRetriggerPolicy(core.RetriggerRestart))
+	entry, _ := bp.AddEntryLinkTo(neu)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entry)
+	<-started
+	_ = brain.TrigLinks(entry)
+
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expect first process canceled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("first process is not canceled")
+	}
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	n := brain.Status().Neurons[neu.GetID()]
+	if n.Process != 2 || n.Canceled != 1 || n.Succeed != 1 || n.Failed != 0 {
+		t.Fatalf("expect one canceled and one succeed activation, got %+v", n)
+	}
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type closerProcessor struct {
+	processFn func(bc processor.BrainContext) error
+	closed    int32
+}
+
+func (p *closerProcessor) Process(bc processor.BrainContext) error {
+	return p.processFn(bc)
+}
+
+func (p *closerProcessor) Clone() processor.Processor {
+	return &closerProcessor{processFn: p.processFn}
+}
+
+func (p *closerProcessor) Close() error {
+	atomic.AddInt32(&p.closed, 1)
+	return nil
+}
+
+func TestShutdownDrain(t *testing.T) {
+	var finished int32
+	started := make(chan struct{})
+	p := &closerProcessor{processFn: func(bc processor.BrainContext) error {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	}}
+	bp := zenmodel.NewBlueprint()
+	slow := bp.AddNeuronWithProcessor(p)
+	_, _ = bp.AddEntryLinkTo(slow)
+
+	brain := brainlite.BuildBrain(bp)
+	_ = brain.Entry()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- brain.Shutdown(ctx)
+	}()
+
+	// triggers are rejected while shutting down
+	time.Sleep(50 * time.Millisecond)
+	if err := brain.Entry(); !errors.Is(err, core.ErrBrainShuttingDown) {
+		t.Fatalf("expect entry rejected while shutting down, got %v", err)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatalf("expect active neuron drained before shutdown returns")
+	}
+	if atomic.LoadInt32(&p.closed) != 1 {
+		t.Fatalf("expect processor closed once, got %d", atomic.LoadInt32(&p.closed))
+	}
+	if brain.GetState() != core.BrainStateShutdown {
+		t.Fatalf("expect brain state %s, got %s", core.BrainStateShutdown, brain.GetState())
+	}
+}
+
+func TestShutdownCancel(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	blocked := bp.AddNeuron(func(bc processor.BrainContext) error {
+		close(started)
+		<-bc.Done()
+		close(canceled)
+		return bc.Err()
+	})
+	_, _ = bp.AddEntryLinkTo(blocked)
+
+	brain := brainlite.BuildBrain(bp)
+	_ = brain.Entry()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := brain.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect shutdown deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expect active neuron canceled")
+	}
+}
+
+// TestShutdownCancelWait checks that shutdown waits canceled neurons return before closing processors,
+// queued activations are discarded
+func TestShutdownCancelWait(t *testing.T) {
+	var returned int32
+	started := make(chan struct{}, 2)
+	p := &closerProcessor{processFn: func(bc processor.BrainContext) error {
+		started <- struct{}{}
+		<-bc.Done()
+		time.Sleep(200 * time.Millisecond)
+		atomic.AddInt32(&returned, 1)
+		return bc.Err()
+	}}
+	bp := zenmodel.NewBlueprint()
+	_, _ = bp.AddEntryLinkTo(bp.AddNeuronWithProcessor(p))
+	_, _ = bp.AddEntryLinkTo(bp.AddNeuronWithProcessor(p))
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronWorkerNum(1))
+	_ = brain.Entry()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := brain.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect shutdown deadline exceeded, got %v", err)
+	}
+	if n := atomic.LoadInt32(&returned); n != 1 {
+		t.Fatalf("expect running neuron returned before shutdown returns and queued one discarded, got %d returned", n)
+	}
+	// processor is shared by both neurons
+	if atomic.LoadInt32(&p.closed) != 2 {
+		t.Fatalf("expect processor closed by both neurons, got %d", atomic.LoadInt32(&p.closed))
+	}
+	if brain.GetState() != core.BrainStateShutdown {
+		t.Fatalf("expect brain state %s, got %s", core.BrainStateShutdown, brain.GetState())
+	}
+}
+
+func TestShutdownConcurrentWithTriggers(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	producer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		for i := 0; i < 5; i++ {
+			_ = bc.SetMemory("count", i)
+			bc.ContinueCast()
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	consumer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		_ = bc.GetMemory("count")
+		return nil
+	})
+	_, _ = bp.AddLink(producer, consumer)
+	entry, _ := bp.AddEntryLinkTo(producer)
+
+	for round := 0; round < 10; round++ {
+		brain := brainlite.BuildBrain(bp)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					_ = brain.Entry()
+					_ = brain.TrigLinks(entry)
+				}
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				_ = brain.Shutdown(ctx)
+			}()
+		}
+		wg.Wait()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := brain.Shutdown(ctx); err != nil {
+			t.Fatalf("shutdown error: %v", err)
+		}
+		cancel()
+	}
+}
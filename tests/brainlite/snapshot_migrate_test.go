@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/brainlocal"
+)
+
+// TestMigrateMemory moves memories from BrainLocal to BrainLite and back
+func TestMigrateMemory(t *testing.T) {
+	local := brainlocal.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = local.Shutdown(context.Background()) }()
+	messages := []snapshotMessage{{Role: "user", Content: "hi"}}
+	_ = local.SetMemory("messages", messages, memoryKey{Name: "k"}, int64(1)<<40)
+
+	var buf bytes.Buffer
+	if err := local.ExportMemory(&buf, nil); err != nil {
+		t.Fatalf("export memory failed: %v", err)
+	}
+	lite := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("migrate-memory"))
+	defer func() { _ = lite.Shutdown(context.Background()) }()
+	if err := lite.ImportMemory(&buf, nil); err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+	if got, ok := lite.GetMemory("messages").([]snapshotMessage); !ok || !reflect.DeepEqual(got, messages) {
+		t.Fatalf("expect messages %v, got %#v", messages, lite.GetMemory("messages"))
+	}
+	// ints are decoded as int whatever their range
+	if got := lite.GetMemory(memoryKey{Name: "k"}); got != 1<<40 {
+		t.Fatalf("expect int memory of struct key, got %#v", got)
+	}
+
+	buf.Reset()
+	if err := lite.ExportMemory(&buf, nil); err != nil {
+		t.Fatalf("export memory failed: %v", err)
+	}
+	back := brainlocal.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = back.Shutdown(context.Background()) }()
+	if err := back.ImportMemory(&buf, nil); err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+	if got := back.GetMemory(memoryKey{Name: "k"}); got != 1<<40 {
+		t.Fatalf("expect int memory of struct key, got %#v", got)
+	}
+}
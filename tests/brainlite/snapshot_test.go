@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/memory"
+)
+
+type snapshotMessage struct {
+	Role    string
+	Content string
+}
+
+func init() {
+	memory.RegisterType([]snapshotMessage{})
+	memory.RegisterType(memoryKey{})
+}
+
+func TestExportImportMemory(t *testing.T) {
+	src := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = src.Shutdown(context.Background()) }()
+	messages := []snapshotMessage{{Role: "user", Content: "hi"}}
+	_ = src.SetMemory("count", 3, "ratio", 0.5, "ok", true, "messages", messages,
+		memoryKey{Name: "k"}, "struct key", "raw", map[string]any{"a": "b"})
+
+	var buf bytes.Buffer
+	if err := src.ExportMemory(&buf, nil); err != nil {
+		t.Fatalf("export memory failed: %v", err)
+	}
+	var again bytes.Buffer
+	_ = src.ExportMemory(&again, nil)
+	if buf.String() != again.String() {
+		t.Fatalf("expect identical snapshots of the same memories")
+	}
+
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("messages", core.AppendReducer)
+	dst := brainlite.BuildBrain(bp)
+	defer func() { _ = dst.Shutdown(context.Background()) }()
+	_ = dst.SetMemory("messages", snapshotMessage{Role: "system"}, "keep", 1)
+	if err := dst.ImportMemory(&buf, nil); err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+
+	if got, ok := dst.GetMemory("count").(int); !ok || got != 3 {
+		t.Fatalf("expect count 3, got %#v", dst.GetMemory("count"))
+	}
+	if got, ok := dst.GetMemory("ok").(bool); !ok || !got {
+		t.Fatalf("expect ok true, got %#v", dst.GetMemory("ok"))
+	}
+	if got := dst.GetMemory("ratio"); got != 0.5 {
+		t.Fatalf("expect ratio 0.5, got %#v", got)
+	}
+	// registered type is restored and reducer is not applied
+	if got, ok := dst.GetMemory("messages").([]snapshotMessage); !ok || !reflect.DeepEqual(got, messages) {
+		t.Fatalf("expect messages %v, got %#v", messages, dst.GetMemory("messages"))
+	}
+	if got := dst.GetMemory(memoryKey{Name: "k"}); got != "struct key" {
+		t.Fatalf("expect memory of struct key, got %#v", got)
+	}
+	if got := dst.GetMemory("raw"); !reflect.DeepEqual(got, map[string]any{"a": "b"}) {
+		t.Fatalf("expect raw map, got %#v", got)
+	}
+	if got := asInt(dst.GetMemory("keep")); got != 1 {
+		t.Fatalf("expect memory not in snapshot kept, got %d", got)
+	}
+}
+
+func TestExportImportMemoryFilter(t *testing.T) {
+	src := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = src.Shutdown(context.Background()) }()
+	_ = src.SetMemory("a", 1, "b", 2, "c", 3)
+
+	var buf bytes.Buffer
+	if err := src.ExportMemory(&buf, memory.KeyFilter("a", []byte("b"))); err != nil {
+		t.Fatalf("export memory failed: %v", err)
+	}
+	dst := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = dst.Shutdown(context.Background()) }()
+	err := dst.ImportMemory(&buf, func(key any) bool {
+		return key != "a"
+	})
+	if err != nil {
+		t.Fatalf("import memory failed: %v", err)
+	}
+
+	keys, _ := dst.ListMemoryKeys()
+	if len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("expect only b imported, got %v", keys)
+	}
+}
+
+func TestImportMemoryInvalidSnapshot(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	if err := brain.ImportMemory(strings.NewReader(`{"version": 2, "memories": []}`), nil); err == nil {
+		t.Fatalf("expect error of unsupported version")
+	}
+	snapshot := `{"version": 1, "memories": [
+		{"key": "a", "key_type": "string", "value": 1, "value_type": "int"},
+		{"key": "b", "key_type": "string", "value": "x", "value_type": "int"}]}`
+	if err := brain.ImportMemory(strings.NewReader(snapshot), nil); err == nil {
+		t.Fatalf("expect error of invalid value")
+	}
+	if brain.ExistMemory("a") {
+		t.Fatalf("expect nothing imported from invalid snapshot")
+	}
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestStatus(t *testing.T) {
+	rec := &orderRecorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	blocker := blockerNeuron(bp, started, release)
+	entryBlocker, _ := bp.AddEntryLinkTo(blocker)
+	queued := bp.AddNeuron(rec.fn("queued"), core.WithPriority(3))
+	entryQueued, _ := bp.AddEntryLinkTo(queued)
+	failed := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return errors.New("failed")
+	})
+	_, _ = bp.AddLink(queued, failed)
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronWorkerNum(1))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	if status := brain.Status(); status.State != core.BrainStateShutdown || status.RunID != "" {
+		t.Fatalf("expect brain not started, got %+v", status)
+	}
+
+	_ = brain.SetMemory("a", 1, "b", 2)
+	_ = brain.TrigLinks(entryBlocker)
+	<-started
+	_ = brain.TrigLinks(entryQueued)
+	waitPending(t, brain, 1)
+
+	status := brain.Status()
+	if status.State != core.BrainStateRunning || status.RunID == "" {
+		t.Fatalf("expect brain running with run ID, got %+v", status)
+	}
+	if n := status.Neurons[blocker.GetID()]; n.State != core.NeuronStateActivated || n.RunID != status.RunID {
+		t.Fatalf("expect blocker active in current run, got %+v", n)
+	}
+	if len(status.Pending) != 1 || status.Pending[0].NeuronID != queued.GetID() || status.Pending[0].Priority != 3 {
+		t.Fatalf("expect queued neuron pending, got %+v", status.Pending)
+	}
+	if l := status.Links[entryBlocker.GetID()]; l.State != core.LinkStateInit || l.To != blocker.GetID() {
+		t.Fatalf("expect entry link of blocker consumed, got %+v", l)
+	}
+	if status.MemoryKeys != 2 {
+		t.Fatalf("expect 2 memory keys, got %d", status.MemoryKeys)
+	}
+
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	last := brain.Status()
+	if last.State != core.BrainStateSleeping || last.RunID != status.RunID || len(last.Pending) != 0 {
+		t.Fatalf("expect brain sleeping after run, got %+v", last)
+	}
+	for id, expect := range map[string]core.NeuronStatus{
+		blocker.GetID(): {Process: 1, Succeed: 1},
+		queued.GetID():  {Process: 1, Succeed: 1},
+		failed.GetID():  {Process: 1, Failed: 1},
+	} {
+		n := last.Neurons[id]
+		if n.State != core.NeuronStateInactive || n.Process != expect.Process ||
+			n.Succeed != expect.Succeed || n.Failed != expect.Failed {
+			t.Fatalf("neuron %s: expect %+v, got %+v", id, expect, n)
+		}
+	}
+}
+
+func TestStatusWhileRunning(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	for i := 0; i < 20; i++ {
+		_, _ = bp.AddEntryLinkTo(bp.AddNeuron(func(bc processor.BrainContext) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		}))
+	}
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_ = brain.Status()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		_ = brain.Entry()
+		waitWithTimeout(t, brain, 10*time.Second)
+	}
+	close(stop)
+	wg.Wait()
+
+	var process int
+	for _, n := range brain.Status().Neurons {
+		process += n.Process
+	}
+	if process != 200 {
+		t.Fatalf("expect 200 activations, got %d", process)
+	}
+}
+
+// TestStatusInSelector checks that selector and condition are not evaluated by maintainer, they can read status
+// of brain and use BrainContextReader as context
+func TestStatusInSelector(t *testing.T) {
+	var brain core.Brain
+	var selected, conditioned int32
+	bp := zenmodel.NewBlueprint()
+	src := bp.AddNeuron(nopProcess, core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+		if ctx, ok := bcr.(context.Context); ok && ctx.Err() == nil && brain.Status().State == core.BrainStateRunning {
+			atomic.AddInt32(&selected, 1)
+		}
+		return "next"
+	}))
+	dest := bp.AddNeuron(nopProcess)
+	_, _ = bp.AddEntryLinkTo(src)
+	next, _ := bp.AddLink(src, dest, core.WithCondition(func(bcr processor.BrainContextReader) bool {
+		if ctx, ok := bcr.(context.Context); ok && ctx.Err() == nil && brain.Status().State == core.BrainStateRunning {
+			atomic.AddInt32(&conditioned, 1)
+		}
+		return true
+	}))
+	_ = src.AddCastGroup("next", next)
+
+	brain = brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if atomic.LoadInt32(&selected) != 1 || atomic.LoadInt32(&conditioned) != 1 {
+		t.Fatalf("expect selector and condition evaluated with status and context, got %d, %d", selected, conditioned)
+	}
+	if n := brain.Status().Neurons[dest.GetID()]; n.Succeed != 1 {
+		t.Fatalf("expect destination processed, got %+v", n)
+	}
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestUpdateMemoryParallel(t *testing.T) {
+	const workers = 20
+	bp := zenmodel.NewBlueprint()
+	for i := 0; i < workers; i++ {
+		worker := bp.AddNeuron(func(bc processor.BrainContext) error {
+			return bc.UpdateMemory(func(tx processor.MemoryTx) error {
+				count := asInt(tx.GetMemory("count"))
+				// widen the window between read and write
+				time.Sleep(time.Millisecond)
+				return tx.SetMemory("count", count+1, "last", bc.GetCurrentNeuronID())
+			})
+		})
+		_, _ = bp.AddEntryLinkTo(worker)
+	}
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronWorkerNum(workers))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("count", 0)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := asInt(brain.GetMemory("count")); got != workers {
+		t.Fatalf("expect count %d, got %d", workers, got)
+	}
+	if !brain.ExistMemory("last") {
+		t.Fatalf("expect memory last exists")
+	}
+}
+
+func TestUpdateMemoryRollback(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("a", 1, "b", 1)
+
+	errAbort := errors.New("abort")
+	err := brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		if err := tx.SetMemory("a", 2); err != nil {
+			return err
+		}
+		tx.DeleteMemory("b")
+		// reads of tx see its own writes
+		if got := asInt(tx.GetMemory("a")); got != 2 {
+			t.Errorf("expect a 2 in tx, got %d", got)
+		}
+		if tx.ExistMemory("b") {
+			t.Errorf("expect b deleted in tx")
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expect abort error, got %v", err)
+	}
+	if a, b := asInt(brain.GetMemory("a")), asInt(brain.GetMemory("b")); a != 1 || b != 1 {
+		t.Fatalf("expect a 1 and b 1 after rollback, got %d and %d", a, b)
+	}
+
+	err = brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		tx.DeleteMemory("b")
+		return tx.SetMemory("a", 2)
+	})
+	if err != nil {
+		t.Fatalf("update memory failed: %v", err)
+	}
+	if got := asInt(brain.GetMemory("a")); got != 2 {
+		t.Fatalf("expect a 2 after commit, got %d", got)
+	}
+	if brain.ExistMemory("b") {
+		t.Fatalf("expect b deleted after commit")
+	}
+}
+
+func TestCompareAndSwapMemory(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("messages", core.AppendReducer)
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	cases := []struct {
+		key     string
+		old     any
+		new     any
+		swapped bool
+	}{
+		{key: "owner", old: nil, new: "alice", swapped: true},
+		{key: "owner", old: nil, new: "bob", swapped: false},
+		{key: "owner", old: "bob", new: "carol", swapped: false},
+		{key: "owner", old: "alice", new: "bob", swapped: true},
+		// reducer is not applied
+		{key: "messages", old: nil, new: []string{"hi"}, swapped: true},
+		{key: "messages", old: []string{"hi"}, new: []string{"hello"}, swapped: true},
+	}
+	for _, c := range cases {
+		swapped, err := brain.CompareAndSwapMemory(c.key, c.old, c.new)
+		if err != nil {
+			t.Fatalf("compare and swap failed: %v", err)
+		}
+		if swapped != c.swapped {
+			t.Fatalf("expect swapped %v of %s from %v to %v, got %v", c.swapped, c.key, c.old, c.new, swapped)
+		}
+	}
+	if got := brain.GetMemory("owner"); got != "bob" {
+		t.Fatalf("expect owner bob, got %v", got)
+	}
+	if swapped, _ := brain.CompareAndSwapMemory("messages", []string{"hello"}, nil); !swapped {
+		t.Fatalf("expect messages is [hello]")
+	}
+}
+
+func TestCompareAndSwapMemoryParallel(t *testing.T) {
+	const workers = 10
+	bp := zenmodel.NewBlueprint()
+	for i := 0; i < workers; i++ {
+		worker := bp.AddNeuron(func(bc processor.BrainContext) error {
+			for {
+				old := asInt(bc.GetMemory("count"))
+				swapped, err := bc.CompareAndSwapMemory("count", old, old+1)
+				if err != nil || swapped {
+					return err
+				}
+			}
+		})
+		_, _ = bp.AddEntryLinkTo(worker)
+	}
+
+	brain := brainlite.BuildBrain(bp, brainlite.WithNeuronWorkerNum(workers))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("count", 0)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := asInt(brain.GetMemory("count")); got != workers {
+		t.Fatalf("expect count %d, got %d", workers, got)
+	}
+}
+
+// TestUnhashableMemoryKey checks that slice and map keys are stored by their JSON, and keys which can not be encoded
+// fail with error instead of panic
+func TestUnhashableMemoryKey(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	if err := brain.SetMemory([]string{"a"}, 1); err != nil {
+		t.Fatalf("set memory of slice key failed: %v", err)
+	}
+	if swapped, err := brain.CompareAndSwapMemory([]any{"a"}, 1, 2); err != nil || !swapped {
+		t.Fatalf("expect memory of slice key swapped, got %v, %v", swapped, err)
+	}
+	err := brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		tx.DeleteMemory(make(chan int))
+		return tx.SetMemory("b", 2)
+	})
+	if err == nil || brain.ExistMemory("b") {
+		t.Fatalf("expect tx fails with chan key and none applied, got %v", err)
+	}
+	brain.DeleteMemory([]string{"a"})
+	if brain.GetMemory([]string{"a"}) != nil || brain.ExistMemory([]string{"a"}) {
+		t.Fatal("expect memory of slice key deleted")
+	}
+}
+
+// TestUpdateMemoryExternalWrite checks that other processes, e.g. Python processors, can not write memory between
+// the reads and the writes of a transaction
+func TestUpdateMemoryExternalWrite(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(), brainlite.WithID("external-tx"))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("count", 1)
+
+	db, err := sql.Open("sqlite3", "external-tx.db?_busy_timeout=0")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	defer db.Close()
+	err = brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		count := asInt(tx.GetMemory("count"))
+		if _, err := db.Exec("UPDATE memory SET value = 100"); err == nil {
+			t.Errorf("expect external write is blocked by transaction")
+		}
+		return tx.SetMemory("count", count+1)
+	})
+	if err != nil {
+		t.Fatalf("update memory failed: %v", err)
+	}
+	if got := asInt(brain.GetMemory("count")); got != 2 {
+		t.Fatalf("expect count 2, got %d", got)
+	}
+
+	if _, err = db.Exec("UPDATE memory SET value = 100"); err != nil {
+		t.Fatalf("external write failed: %v", err)
+	}
+	if swapped, err := brain.CompareAndSwapMemory("count", 2, 3); err != nil || swapped {
+		t.Fatalf("expect swap fails after external write, got %v, %v", swapped, err)
+	}
+	if got := asInt(brain.GetMemory("count")); got != 100 {
+		t.Fatalf("expect count 100 written externally, got %d", got)
+	}
+}
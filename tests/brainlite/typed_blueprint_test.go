@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/memory"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type agentState struct {
+	Question string         `json:"question"`
+	Drafts   []string       `json:"drafts"`
+	Scores   map[string]int `json:"scores"`
+	Attempts int            `json:"attempts"`
+	// not in memory
+	Scratch string `json:"-"`
+}
+
+func (s *agentState) Validate() error {
+	if s.Attempts < 0 {
+		return errors.New("attempts must not be negative")
+	}
+	return nil
+}
+
+func TestTypedBlueprint(t *testing.T) {
+	bp := zenmodel.NewTypedBlueprint[agentState]()
+	draft := bp.AddStateNeuron(func(bc processor.BrainContext, state *memory.State[agentState]) error {
+		return state.Update(func(s *agentState) error {
+			s.Attempts++
+			s.Drafts = append(s.Drafts, s.Question+"?")
+			if s.Scores == nil {
+				s.Scores = map[string]int{}
+			}
+			s.Scores[s.Question] = s.Attempts * 10
+			return nil
+		})
+	})
+	again, _ := bp.AddLink(draft, draft)
+	done, _ := bp.AddEndLinkFrom(draft)
+	_, _ = bp.AddEntryLinkTo(draft)
+	_ = draft.AddCastGroup("again", again)
+	_ = draft.AddCastGroup("done", done)
+	draft.BindCastGroupSelectFunc(bp.StateSelectFn(func(s agentState) string {
+		if s.Attempts < 3 {
+			return "again"
+		}
+		return "done"
+	}))
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	state := bp.State(brain)
+	if err := state.Set(agentState{Question: "why", Scratch: "dropped"}); err != nil {
+		t.Fatalf("set initial state error: %v", err)
+	}
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	got, err := state.Get()
+	if err != nil {
+		t.Fatalf("get state error: %v", err)
+	}
+	expect := agentState{
+		Question: "why",
+		Drafts:   []string{"why?", "why?", "why?"},
+		Scores:   map[string]int{"why": 30},
+		Attempts: 3,
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect state %+v, got %+v", expect, got)
+	}
+	if !brain.ExistMemory("drafts") || brain.ExistMemory("Scratch") {
+		t.Fatal("expect fields stored by JSON name and skipped fields not stored")
+	}
+
+	// field-level updates are validated
+	if err = state.SetField("Attempts", "three"); err == nil {
+		t.Fatal("expect type mismatch error")
+	}
+	if err = state.SetField("attempts", -1); err == nil {
+		t.Fatal("expect validation error")
+	}
+	if err = state.SetField("unknown", 1); err == nil {
+		t.Fatal("expect unknown field error")
+	}
+	if err = state.SetField("Attempts", 5); err != nil {
+		t.Fatalf("set field error: %v", err)
+	}
+	if attempts, _ := state.GetField("attempts"); attempts != 5 {
+		t.Fatalf("expect attempts 5, got %v", attempts)
+	}
+
+	// the whole state is serializable
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("marshal state error: %v", err)
+	}
+	brain.ClearMemory()
+	if err = state.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal state error: %v", err)
+	}
+	expect.Attempts = 5
+	if got, _ = state.Get(); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect restored state %+v, got %+v", expect, got)
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+)
+
+// TestWatchMemoryExternalWrite writes the SQLite file as Python processors do
+func TestWatchMemoryExternalWrite(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint(),
+		brainlite.WithID("watch-external"),
+		brainlite.WithMemoryWatchInterval(10*time.Millisecond))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := brain.WatchMemory(ctx, "text_to_speech")
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", "watch-external.db")
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	defer db.Close()
+	h := sha256.Sum256([]byte("text_to_speech"))
+	hashedKey := int64(binary.BigEndian.Uint64(h[:8]))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	if _, err = tx.Exec("INSERT OR REPLACE INTO memory_writer (id, writer) VALUES (1, ?)", "py-neuron"); err != nil {
+		t.Fatalf("set writer failed: %v", err)
+	}
+	if _, err = tx.Exec("INSERT OR REPLACE INTO memory (key, value, type) VALUES (?, ?, ?)", hashedKey, `"hello"`, "string"); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	got, _ := receiveChange(t, changes, 5*time.Second)
+	expect := core.MemoryChange{Key: "text_to_speech", New: "hello", NeuronID: "py-neuron"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect change %+v, got %+v", expect, got)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlite"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func receiveChange(t *testing.T, changes <-chan core.MemoryChange, timeout time.Duration) (core.MemoryChange, bool) {
+	t.Helper()
+	select {
+	case change, ok := <-changes:
+		return change, ok
+	case <-time.After(timeout):
+		t.Fatalf("no memory change received after %s", timeout)
+		return core.MemoryChange{}, false
+	}
+}
+
+func TestWatchMemory(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	speaker := bp.AddNeuron(func(bc processor.BrainContext) error {
+		if err := bc.SetMemory("text", "hello", "other", 1); err != nil {
+			return err
+		}
+		if err := bc.SetMemory("text", "world"); err != nil {
+			return err
+		}
+		bc.DeleteMemory("text")
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(speaker)
+
+	brain := brainlite.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := brain.WatchMemory(ctx, "text")
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	expect := []core.MemoryChange{
+		{Key: "text", New: "hello", NeuronID: speaker.GetID()},
+		{Key: "text", Old: "hello", New: "world", NeuronID: speaker.GetID()},
+		{Key: "text", Old: "world", Deleted: true, NeuronID: speaker.GetID()},
+	}
+	for _, e := range expect {
+		got, ok := receiveChange(t, changes, 5*time.Second)
+		if !ok {
+			t.Fatalf("expect change %+v, but channel closed", e)
+		}
+		if !reflect.DeepEqual(got, e) {
+			t.Fatalf("expect change %+v, got %+v", e, got)
+		}
+	}
+}
+
+func TestWatchMemoryAllAndCancel(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := brain.WatchMemory(ctx)
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+
+	_ = brain.SetMemory("a", 1)
+	got, _ := receiveChange(t, changes, 5*time.Second)
+	if expect := (core.MemoryChange{Key: "a", New: 1}); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect change %+v, got %+v", expect, got)
+	}
+
+	cancel()
+	for {
+		if _, ok := receiveChange(t, changes, 5*time.Second); !ok {
+			break
+		}
+	}
+}
+
+func TestWatchMemoryShutdown(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	changes, err := brain.WatchMemory(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+	_ = brain.SetMemory("a", 1)
+	_ = brain.Shutdown(context.Background())
+
+	for {
+		if _, ok := receiveChange(t, changes, 5*time.Second); !ok {
+			break
+		}
+	}
+}
+
+func TestWatchMemoryUnhashableKey(t *testing.T) {
+	brain := brainlite.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	if changes, err := brain.WatchMemory(context.Background(), "a", make(chan int)); err == nil || changes != nil {
+		t.Fatal("expect error of chan key")
+	}
+	// slice key is stored by its JSON
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := brain.WatchMemory(ctx, []string{"a"})
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+	if err = brain.SetMemory([]any{"a"}, 1); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if change, _ := receiveChange(t, changes, 5*time.Second); asInt(change.New) != 1 {
+		t.Fatalf("expect change of slice key, got %+v", change)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -89,6 +90,6 @@ func TestBranch(t *testing.T) {
 	_ = brain.EntryWithMemory("category", "NOT-Defined")
 	brain.Wait()
 
-	brain.Shutdown()
+	_ = brain.Shutdown(context.Background())
 }
 
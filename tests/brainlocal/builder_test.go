@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestBuilder(t *testing.T) {
+	recorder := &orderRecorder{}
+	builder := zenmodel.NewBuilder()
+	plan := builder.Neuron(recorder.fn("plan"))
+	search := builder.Neuron(recorder.fn("search"))
+	calc := builder.Neuron(recorder.fn("calc"))
+	merge := builder.Neuron(recorder.fn("merge"))
+	refine := builder.Neuron(func(bc processor.BrainContext) error {
+		_ = recorder.fn("refine")(bc)
+		return bc.SetMemory("rounds", asInt(bc.GetMemory("rounds"))+1)
+	})
+	review := builder.Neuron(recorder.fn("review"))
+
+	bp, err := builder.
+		Entry(plan).
+		Parallel(plan, merge, search, calc).
+		Chain(merge, refine).
+		Loop(refine, func(bcr processor.BrainContextReader) bool {
+			return asInt(bcr.GetMemory("rounds")) >= 3
+		}, review).
+		Branch(review, processor.NewFuncSelector(func(bcr processor.BrainContextReader) string {
+			return "approved"
+		}), map[string]core.Neuron{"approved": nil, "rejected": plan}).
+		Build()
+	if err != nil {
+		t.Fatalf("build blueprint error: %v", err)
+	}
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("rounds", 0)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	order := recorder.list()
+	if len(order) != 8 {
+		t.Fatalf("expect 8 activations, got %v", order)
+	}
+	parallel := append([]string(nil), order[1:3]...)
+	sort.Strings(parallel)
+	expect := []string{"plan", "calc", "search", "merge", "refine", "refine", "refine", "review"}
+	got := append(append([]string{order[0]}, parallel...), order[3:]...)
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect order %v, got %v", expect, order)
+	}
+	if outcome := brain.GetOutcome(); outcome != "approved" {
+		t.Fatalf("expect outcome approved, got %q", outcome)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	other := zenmodel.NewBlueprint().AddNeuron(nopProcess)
+	builder := zenmodel.NewBuilder()
+	a := builder.Neuron(nopProcess)
+	b := builder.Neuron(nopProcess)
+
+	_, err := builder.
+		Chain(a, other).
+		Parallel(a, nil, b).
+		Loop(b, nil, nil).
+		Link(a, b).
+		Build()
+	var buildErr *zenmodel.BuildError
+	if !errors.As(err, &buildErr) || len(buildErr.Errs) != 3 {
+		t.Fatalf("expect 3 collected errors, got %v", err)
+	}
+	if errors.Unwrap(err) != buildErr.Errs[0] {
+		t.Fatalf("expect build error unwraps to the first error, got %v", errors.Unwrap(err))
+	}
+}
+
+func TestBuilderBranchErrors(t *testing.T) {
+	other := zenmodel.NewBlueprint().AddNeuron(nopProcess)
+	builder := zenmodel.NewBuilder()
+	a := builder.Neuron(nopProcess)
+	selector := processor.NewFuncSelector(func(bcr processor.BrainContextReader) string {
+		return "next"
+	})
+
+	// selector is not bound if branch failed
+	_, err := builder.Branch(a, selector, map[string]core.Neuron{"next": other}).Build()
+	if _, ok := a.GetSelector().(*processor.DefaultSelector); err == nil || !ok {
+		t.Fatalf("expect branch failed without selector bound, got %v, %T", err, a.GetSelector())
+	}
+
+	// loop links of branch source are never cast
+	builder = zenmodel.NewBuilder()
+	a = builder.Neuron(nopProcess)
+	b := builder.Neuron(nopProcess)
+	_, err = builder.
+		Entry(a).
+		Branch(a, selector, map[string]core.Neuron{"next": b, "done": nil}).
+		Loop(a, func(bcr processor.BrainContextReader) bool { return true }, nil).
+		Build()
+	var buildErr *zenmodel.BuildError
+	if !errors.As(err, &buildErr) || len(buildErr.Errs) != 1 || !strings.Contains(err.Error(), a.GetID()) {
+		t.Fatalf("expect loop on branch source rejected, got %v", err)
+	}
+}
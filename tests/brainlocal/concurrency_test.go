@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestConcurrentParallelAndWait(t *testing.T) {
+	var genCnt int32
+	bp := zenmodel.NewBlueprint()
+	input := bp.AddNeuron(inputFn)
+	poetryTemplate := bp.AddNeuron(poetryFn)
+	jokeTemplate := bp.AddNeuron(jokeFn)
+	generate := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(&genCnt, 1)
+		return nil
+	})
+
+	inputIn, _ := bp.AddLink(input, generate)
+	poetryIn, _ := bp.AddLink(poetryTemplate, generate)
+	jokeIn, _ := bp.AddLink(jokeTemplate, generate)
+
+	entryInput, _ := bp.AddEntryLinkTo(input)
+	entryPoetry, _ := bp.AddEntryLinkTo(poetryTemplate)
+
+	_ = generate.AddTriggerGroup(inputIn, poetryIn)
+	_ = generate.AddTriggerGroup(inputIn, jokeIn)
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithNeuronWorkerNum(8))
+
+	const rounds = 50
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		for _, l := range []core.Link{entryPoetry, entryInput} {
+			wg.Add(1)
+			go func(l core.Link) {
+				defer wg.Done()
+				_ = brain.TrigLinks(l)
+			}(l)
+		}
+		wg.Wait()
+		waitWithTimeout(t, brain, 10*time.Second)
+	}
+
+	if got := atomic.LoadInt32(&genCnt); got != rounds {
+		t.Fatalf("expect generate neuron processed %d times, got %d", rounds, got)
+	}
+	_ = brain.Shutdown(context.Background())
+}
+
+func TestConcurrentDiamonds(t *testing.T) {
+	const width, depth, rounds = 32, 4, 10
+	var processCnt, joinCnt int32
+
+	bp := zenmodel.NewBlueprint()
+	src := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(src)
+	// src -> width workers -> join -> width workers -> join ...
+	for d := 0; d < depth; d++ {
+		join := bp.AddNeuron(func(bc processor.BrainContext) error {
+			atomic.AddInt32(&joinCnt, 1)
+			return nil
+		})
+		joinLinks := make([]core.Link, 0, width)
+		for i := 0; i < width; i++ {
+			worker := bp.AddNeuron(func(bc processor.BrainContext) error {
+				atomic.AddInt32(&processCnt, 1)
+				return bc.SetMemory(bc.GetCurrentNeuronID(), time.Now().UnixNano())
+			})
+			_, _ = bp.AddLink(src, worker)
+			l, _ := bp.AddLink(worker, join)
+			joinLinks = append(joinLinks, l)
+		}
+		_ = join.AddTriggerGroup(joinLinks...)
+		src = join
+	}
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithNeuronWorkerNum(16))
+	for i := 0; i < rounds; i++ {
+		_ = brain.Entry()
+		waitWithTimeout(t, brain, 10*time.Second)
+	}
+
+	if got := atomic.LoadInt32(&processCnt); got != width*depth*rounds {
+		t.Fatalf("expect workers processed %d times, got %d", width*depth*rounds, got)
+	}
+	if got := atomic.LoadInt32(&joinCnt); got != depth*rounds {
+		t.Fatalf("expect joins processed %d times, got %d", depth*rounds, got)
+	}
+	_ = brain.Shutdown(context.Background())
+}
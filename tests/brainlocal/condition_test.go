@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestLinkCondition(t *testing.T) {
+	cases := []struct {
+		name     string
+		score    int
+		fallback bool
+		expect   []string
+	}{
+		{name: "one matches", score: 90, expect: []string{"pass"}},
+		{name: "several match", score: 100, expect: []string{"pass", "perfect"}},
+		{name: "none matches", score: 30, expect: nil},
+		{name: "none matches with unconditional link", score: 30, fallback: true, expect: []string{"review"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := &orderRecorder{}
+			bp := zenmodel.NewBlueprint()
+			grade := bp.AddNeuron(nopProcess)
+			pass := bp.AddNeuron(recorder.fn("pass"))
+			perfect := bp.AddNeuron(recorder.fn("perfect"))
+			review := bp.AddNeuron(recorder.fn("review"))
+			_, _ = bp.AddEntryLinkTo(grade)
+			_, _ = bp.AddLink(grade, pass, core.WithCondition(func(bcr processor.BrainContextReader) bool {
+				return asInt(bcr.GetMemory("score")) >= 60
+			}))
+			_, _ = bp.AddLink(grade, perfect, core.WithCondition(func(bcr processor.BrainContextReader) bool {
+				return asInt(bcr.GetMemory("score")) == 100
+			}))
+			if c.fallback {
+				_, _ = bp.AddLink(grade, review)
+			}
+
+			brain := brainlocal.BuildBrain(bp)
+			defer func() { _ = brain.Shutdown(context.Background()) }()
+			_ = brain.EntryWithMemory("score", c.score)
+			waitWithTimeout(t, brain, 10*time.Second)
+
+			got := recorder.list()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, c.expect) {
+				t.Fatalf("expect %v processed, got %v", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestLinkConditionLoop(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	count := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return bc.SetMemory("count", asInt(bc.GetMemory("count"))+1)
+	})
+	_, _ = bp.AddEntryLinkTo(count)
+	_, _ = bp.AddLink(count, count, core.WithCondition(func(bcr processor.BrainContextReader) bool {
+		return asInt(bcr.GetMemory("count")) < 3
+	}))
+	_, _ = bp.AddEndLinkFrom(count, core.WithOutcome("done"), core.WithCondition(func(bcr processor.BrainContextReader) bool {
+		return asInt(bcr.GetMemory("count")) >= 3
+	}))
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("count", 0)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := asInt(brain.GetMemory("count")); got != 3 {
+		t.Fatalf("expect count 3, got %d", got)
+	}
+	if outcome := brain.GetOutcome(); outcome != "done" {
+		t.Fatalf("expect outcome done, got %q", outcome)
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func nopProcess(bc processor.BrainContext) error {
+	return nil
+}
+
+func TestMaxSteps(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	a := bp.AddNeuron(nopProcess)
+	b := bp.AddNeuron(nopProcess)
+	_, _ = bp.AddEntryLinkTo(a)
+	_, _ = bp.AddLink(a, b)
+	_, _ = bp.AddLink(b, a)
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithMaxSteps(10))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	result := brain.GetRunResult()
+	var limitErr *core.LimitError
+	if !errors.Is(result.Err, core.ErrMaxStepsExceeded) || !errors.As(result.Err, &limitErr) || limitErr.NeuronID == "" {
+		t.Fatalf("expect run ends with max steps exceeded, got %v", result.Err)
+	}
+	status := brain.Status()
+	if total := status.Neurons[a.GetID()].Process + status.Neurons[b.GetID()].Process; total != 10 {
+		t.Fatalf("expect 10 activations, got %d", total)
+	}
+}
+
+func TestMaxActivations(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	a := bp.AddNeuron(nopProcess, core.WithMaxActivations(3))
+	b := bp.AddNeuron(nopProcess)
+	_, _ = bp.AddEntryLinkTo(a)
+	_, _ = bp.AddLink(a, b)
+	_, _ = bp.AddLink(b, a)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	// limit is per run
+	for run := 1; run <= 2; run++ {
+		_ = brain.Entry()
+		waitWithTimeout(t, brain, 10*time.Second)
+
+		result := brain.GetRunResult()
+		var limitErr *core.LimitError
+		if !errors.Is(result.Err, core.ErrMaxActivationsExceeded) || !errors.As(result.Err, &limitErr) ||
+			limitErr.NeuronID != a.GetID() {
+			t.Fatalf("expect run ends with max activations of %s exceeded, got %v", a.GetID(), result.Err)
+		}
+		if n := brain.Status().Neurons[a.GetID()]; n.Process != 3*run {
+			t.Fatalf("expect %d activations, got %+v", 3*run, n)
+		}
+	}
+}
+
+func TestLimitCastGroup(t *testing.T) {
+	rec := &orderRecorder{}
+	bp := zenmodel.NewBlueprint()
+	a := bp.AddNeuron(rec.fn("a"),
+		core.WithMaxActivations(3),
+		core.WithLimitCastGroup("done"),
+		core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+			return "loop"
+		}))
+	b := bp.AddNeuron(rec.fn("b"))
+	summary := bp.AddNeuron(rec.fn("summary"))
+	_, _ = bp.AddEntryLinkTo(a)
+	aToB, _ := bp.AddLink(a, b)
+	aToSummary, _ := bp.AddLink(a, summary)
+	_, _ = bp.AddLink(b, a)
+	_ = a.AddCastGroup("loop", aToB)
+	_ = a.AddCastGroup("done", aToSummary)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	result := brain.GetRunResult()
+	if result.Err != nil {
+		t.Fatalf("expect run routed to limit cast group without error, got %v", result.Err)
+	}
+	if result.Limit == nil || result.Limit.NeuronID != a.GetID() || !errors.Is(result.Limit, core.ErrMaxActivationsExceeded) {
+		t.Fatalf("expect max activations of %s recorded in run result, got %v", a.GetID(), result.Limit)
+	}
+	order := rec.list()
+	if len(order) != 7 || order[6] != "summary" {
+		t.Fatalf("expect a,b looped 3 times then summary, got %v", order)
+	}
+}
+
+// TestMaxStepsLimitCastGroup routes to summary neuron when max steps is hit, summary runs beyond max steps
+func TestMaxStepsLimitCastGroup(t *testing.T) {
+	rec := &orderRecorder{}
+	bp := zenmodel.NewBlueprint()
+	a := bp.AddNeuron(rec.fn("a"),
+		core.WithLimitCastGroup("done"),
+		core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+			return "loop"
+		}))
+	b := bp.AddNeuron(rec.fn("b"),
+		core.WithLimitCastGroup("done"),
+		core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+			return "loop"
+		}))
+	summary := bp.AddNeuron(rec.fn("summary"))
+	_, _ = bp.AddEntryLinkTo(a)
+	aToB, _ := bp.AddLink(a, b)
+	aToSummary, _ := bp.AddLink(a, summary)
+	bToA, _ := bp.AddLink(b, a)
+	bToSummary, _ := bp.AddLink(b, summary)
+	_ = a.AddCastGroup("loop", aToB)
+	_ = a.AddCastGroup("done", aToSummary)
+	_ = b.AddCastGroup("loop", bToA)
+	_ = b.AddCastGroup("done", bToSummary)
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithMaxSteps(5))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	result := brain.GetRunResult()
+	if result.Err != nil {
+		t.Fatalf("expect run routed to limit cast group without error, got %v", result.Err)
+	}
+	if result.Limit == nil || result.Limit.NeuronID != b.GetID() || !errors.Is(result.Limit, core.ErrMaxStepsExceeded) {
+		t.Fatalf("expect max steps hit by %s recorded in run result, got %v", b.GetID(), result.Limit)
+	}
+	order := rec.list()
+	if len(order) != 6 || order[5] != "summary" {
+		t.Fatalf("expect 5 steps of a,b then summary, got %v", order)
+	}
+}
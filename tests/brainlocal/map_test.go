@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestMapReduce(t *testing.T) {
+	var running, maxRunning int32
+	bp := zenmodel.NewBlueprint()
+	mapper := bp.AddNeuron(func(bc processor.BrainContext) error {
+		cur := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if cur <= max || atomic.CompareAndSwapInt32(&maxRunning, max, cur) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		item := asInt(bc.GetMemory(core.MapItemMemoryKey))
+		factor := asInt(bc.GetMemory("factor"))
+		// scoped memory is only visible to current item
+		_ = bc.SetMemory("factor", 0)
+		return bc.SetMemory(core.MapResultMemoryKey, item*factor)
+	}, core.WithMap("items", "results"))
+	reducer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		var sum int
+		for _, r := range bc.GetMemory("results").([]any) {
+			sum += asInt(r)
+		}
+		return bc.SetMemory("sum", sum)
+	})
+	_, _ = bp.AddEntryLinkTo(mapper)
+	_, _ = bp.AddLink(mapper, reducer)
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithNeuronWorkerNum(4))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("items", []int{1, 2, 3, 4, 5, 6, 7, 8}, "factor", 10)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if sum := asInt(brain.GetMemory("sum")); sum != 360 {
+		t.Fatalf("expect sum 360, got %v", sum)
+	}
+	results := brain.GetMemory("results").([]any)
+	if len(results) != 8 || asInt(results[0]) != 10 || asInt(results[7]) != 80 {
+		t.Fatalf("expect results in item order, got %v", results)
+	}
+	if factor := asInt(brain.GetMemory("factor")); factor != 10 {
+		t.Fatalf("expect scoped memory not written to brain, got factor %v", factor)
+	}
+	if max := atomic.LoadInt32(&maxRunning); max < 2 {
+		t.Fatalf("expect items processed in parallel, got max %d running", max)
+	}
+}
+
+func TestMapItemFailed(t *testing.T) {
+	var reduced int32
+	bp := zenmodel.NewBlueprint()
+	mapper := bp.AddNeuron(func(bc processor.BrainContext) error {
+		if asInt(bc.GetMemory(core.MapIndexMemoryKey)) == 1 {
+			return errors.New("item failed")
+		}
+		return nil
+	}, core.WithMap("items", "results"))
+	reducer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(&reduced, 1)
+		return nil
+	})
+	_, _ = bp.AddEntryLinkTo(mapper)
+	_, _ = bp.AddLink(mapper, reducer)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.EntryWithMemory("items", []string{"a", "b", "c"})
+	// failed neuron does not cast, wait it processed instead of brain sleeping
+	deadline := time.Now().Add(10 * time.Second)
+	for brain.Status().Neurons[mapper.GetID()].Failed != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expect map neuron failed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&reduced) != 0 || brain.ExistMemory("results") {
+		t.Fatal("expect no results reduced when map item failed")
+	}
+}
+
+// asInt converts number memory to int, memory may be decoded from JSON as float64
+func asInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return -1
+	}
+}
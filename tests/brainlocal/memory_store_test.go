@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestMemoryNotDropped(t *testing.T) {
+	const keys = 100000
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	err := brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		for i := 0; i < keys; i++ {
+			if err := tx.SetMemory(fmt.Sprintf("key-%d", i), i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("update memory failed: %v", err)
+	}
+	for i := 0; i < keys; i++ {
+		if got := asInt(brain.GetMemory(fmt.Sprintf("key-%d", i))); got != i {
+			t.Fatalf("expect key-%d %d, got %d", i, i, got)
+		}
+	}
+	if got := brain.Status().MemoryKeys; got != keys {
+		t.Fatalf("expect %d memory keys, got %d", keys, got)
+	}
+}
+
+func TestMemoryQuota(t *testing.T) {
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint(), brainlocal.WithMemoryQuota(2, 0))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	if err := brain.SetMemory("a", 1, "b", 2); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if err := brain.SetMemory("a", 10, "c", 3); !errors.Is(err, core.ErrMemoryQuotaExceeded) {
+		t.Fatalf("expect quota exceeded, got %v", err)
+	}
+	// none of the writes is applied
+	if got := asInt(brain.GetMemory("a")); got != 1 {
+		t.Fatalf("expect a 1, got %d", got)
+	}
+	if brain.ExistMemory("c") {
+		t.Fatalf("expect c not exists")
+	}
+
+	// quota is checked with the result of transaction
+	err := brain.UpdateMemory(func(tx processor.MemoryTx) error {
+		tx.DeleteMemory("a")
+		return tx.SetMemory("c", 3)
+	})
+	if err != nil {
+		t.Fatalf("update memory failed: %v", err)
+	}
+	if brain.ExistMemory("a") || !brain.ExistMemory("c") {
+		t.Fatalf("expect a deleted and c set")
+	}
+}
+
+func TestMemoryByteQuota(t *testing.T) {
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint(), brainlocal.WithMemoryQuota(0, 10))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	// "12345678" is 10 bytes in JSON
+	if err := brain.SetMemory("s", "12345678"); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if err := brain.SetMemory("t", 1); !errors.Is(err, core.ErrMemoryQuotaExceeded) {
+		t.Fatalf("expect quota exceeded, got %v", err)
+	}
+	if got := brain.Status().MemoryBytes; got != 10 {
+		t.Fatalf("expect 10 memory bytes, got %d", got)
+	}
+	if err := brain.SetMemory("s", "1"); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if err := brain.SetMemory("t", 1); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if got := brain.Status().MemoryBytes; got != 4 {
+		t.Fatalf("expect 4 memory bytes, got %d", got)
+	}
+}
+
+func TestMemoryQuotaInNeuron(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	writer := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return bc.SetMemory("a", 1, "b", 2)
+	})
+	_, _ = bp.AddEntryLinkTo(writer)
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithMemoryQuota(1, 0))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 5*time.Second)
+
+	if brain.Status().Neurons[writer.GetID()].Failed != 1 {
+		t.Fatalf("expect writer failed")
+	}
+	if got := brain.Status().MemoryKeys; got != 0 {
+		t.Fatalf("expect 0 memory keys, got %d", got)
+	}
+}
+
+func TestCacheMemory(t *testing.T) {
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint(), brainlocal.WithCacheMemory(1e4, 1<<20))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := brain.WatchMemory(ctx)
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+
+	_ = brain.SetMemory("a", 1)
+	if got := asInt(brain.GetMemory("a")); got != 1 {
+		t.Fatalf("expect a 1, got %d", got)
+	}
+	if got := brain.Status().MemoryKeys; got != 1 {
+		t.Fatalf("expect 1 memory key, got %d", got)
+	}
+	brain.ClearMemory()
+	if brain.ExistMemory("a") {
+		t.Fatalf("expect a cleared")
+	}
+
+	if change, _ := receiveChange(t, changes, time.Second); change.Key != "a" {
+		t.Fatalf("expect change of a, got %+v", change)
+	}
+	// ristretto can not list keys
+	if change, _ := receiveChange(t, changes, time.Second); change.Key != nil || !change.Deleted {
+		t.Fatalf("expect clear without key, got %+v", change)
+	}
+}
+
+func TestClearMemoryWatch(t *testing.T) {
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.SetMemory("a", 1, "b", 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := brain.WatchMemory(ctx, "a")
+	if err != nil {
+		t.Fatalf("watch memory failed: %v", err)
+	}
+	brain.ClearMemory()
+
+	change, _ := receiveChange(t, changes, time.Second)
+	if change.Key != "a" || !change.Deleted || asInt(change.Old) != 1 {
+		t.Fatalf("expect a deleted, got %+v", change)
+	}
+}
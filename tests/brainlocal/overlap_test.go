@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestOverlappingTriggersMerged(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	neu := bp.AddNeuron(func(bc processor.BrainContext) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	entry, _ := bp.AddEntryLinkTo(neu)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entry)
+	<-started
+	for i := 0; i < 3; i++ {
+		_ = brain.TrigLinks(entry)
+	}
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expect triggers while active merged into one activation, got %d activations", got)
+	}
+}
+
+func TestCastToActiveNeuron(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	fast := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return nil
+	})
+	slow := bp.AddNeuron(func(bc processor.BrainContext) error {
+		<-started // cast after dest neuron activated by fast
+		return nil
+	})
+	dest := bp.AddNeuron(func(bc processor.BrainContext) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	entryFast, _ := bp.AddEntryLinkTo(fast)
+	entrySlow, _ := bp.AddEntryLinkTo(slow)
+	_, _ = bp.AddLink(fast, dest)
+	slowToDest, _ := bp.AddLink(slow, dest)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entryFast, entrySlow)
+	<-started
+	// wait slow neuron cast to the active dest neuron
+	deadline := time.Now().Add(10 * time.Second)
+	for brain.Status().Neurons[slow.GetID()].Succeed == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("slow neuron is not processed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expect dest neuron activated again by link %s, got %d activations", slowToDest.GetID(), got)
+	}
+}
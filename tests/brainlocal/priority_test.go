@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type orderRecorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *orderRecorder) fn(name string) func(bc processor.BrainContext) error {
+	return func(bc processor.BrainContext) error {
+		r.mu.Lock()
+		r.order = append(r.order, name)
+		r.mu.Unlock()
+		return nil
+	}
+}
+
+func (r *orderRecorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// blockerNeuron occupies a worker until release is closed
+func blockerNeuron(bp core.Blueprint, started, release chan struct{}, withOpts ...core.NeuronOption) core.Neuron {
+	return bp.AddNeuron(func(bc processor.BrainContext) error {
+		close(started)
+		<-release
+		return nil
+	}, withOpts...)
+}
+
+// waitPending waits until n activations are queued, so that they are scheduled by priority together
+func waitPending(t *testing.T, brain core.Brain, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(brain.Status().Pending) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect %d activations queued, got %v", n, brain.Status().Pending)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriorityScheduling(t *testing.T) {
+	rec := &orderRecorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	blocker := blockerNeuron(bp, started, release)
+	entryBlocker, _ := bp.AddEntryLinkTo(blocker)
+	queued := make([]core.Link, 0)
+	for _, name := range []string{"low1", "low2", "low3"} {
+		l, _ := bp.AddEntryLinkTo(bp.AddNeuron(rec.fn(name)))
+		queued = append(queued, l)
+	}
+	high := bp.AddNeuron(rec.fn("high"), core.WithPriority(10))
+	l, _ := bp.AddEntryLinkTo(high)
+	queued = append(queued, l)
+
+	brain := brainlocal.BuildBrain(bp,
+		brainlocal.WithNeuronWorkerNum(1),
+		brainlocal.WithNeuronPriorityAging(0),
+	)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entryBlocker)
+	<-started
+	_ = brain.TrigLinks(queued...)
+	waitPending(t, brain, len(queued))
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	order := rec.list()
+	if len(order) != 4 || order[0] != "high" {
+		t.Fatalf("expect high priority neuron processed first, got %v", order)
+	}
+}
+
+func TestPriorityAging(t *testing.T) {
+	rec := &orderRecorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	blocker := blockerNeuron(bp, started, release)
+	entryBlocker, _ := bp.AddEntryLinkTo(blocker)
+	entryLow, _ := bp.AddEntryLinkTo(bp.AddNeuron(rec.fn("low")))
+	entryHigh, _ := bp.AddEntryLinkTo(bp.AddNeuron(rec.fn("high"), core.WithPriority(10)))
+
+	brain := brainlocal.BuildBrain(bp,
+		brainlocal.WithNeuronWorkerNum(1),
+		brainlocal.WithNeuronPriorityAging(time.Millisecond),
+	)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entryBlocker)
+	<-started
+	_ = brain.TrigLinks(entryLow)
+	// low priority neuron waits long enough to exceed high priority one
+	time.Sleep(100 * time.Millisecond)
+	_ = brain.TrigLinks(entryHigh)
+	waitPending(t, brain, 2)
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	order := rec.list()
+	if len(order) != 2 || order[0] != "low" {
+		t.Fatalf("expect aged low priority neuron processed first, got %v", order)
+	}
+}
+
+// TestPrioritySmallAging checks that priority is not lost in the sort key when aging interval is tiny
+func TestPrioritySmallAging(t *testing.T) {
+	rec := &orderRecorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	blocker := blockerNeuron(bp, started, release)
+	entryBlocker, _ := bp.AddEntryLinkTo(blocker)
+	entryLow, _ := bp.AddEntryLinkTo(bp.AddNeuron(rec.fn("low")))
+	// one second of aging at nanosecond interval
+	entryHigh, _ := bp.AddEntryLinkTo(bp.AddNeuron(rec.fn("high"), core.WithPriority(int(time.Second))))
+
+	brain := brainlocal.BuildBrain(bp,
+		brainlocal.WithNeuronWorkerNum(1),
+		brainlocal.WithNeuronPriorityAging(time.Nanosecond),
+	)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entryBlocker)
+	<-started
+	_ = brain.TrigLinks(entryLow)
+	_ = brain.TrigLinks(entryHigh)
+	waitPending(t, brain, 2)
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	order := rec.list()
+	if len(order) != 2 || order[0] != "high" {
+		t.Fatalf("expect high priority neuron processed first with small aging, got %v", order)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	processed := make(chan struct{})
+	bp := zenmodel.NewBlueprint()
+	slowIO := blockerNeuron(bp, started, release, core.WithWorkerPool("io"))
+	entryIO, _ := bp.AddEntryLinkTo(slowIO)
+	router := bp.AddNeuron(func(bc processor.BrainContext) error {
+		close(processed)
+		return nil
+	})
+	entryRouter, _ := bp.AddEntryLinkTo(router)
+
+	brain := brainlocal.BuildBrain(bp,
+		brainlocal.WithNeuronWorkerNum(1),
+		brainlocal.WithNeuronWorkerPool("io", 1),
+	)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.TrigLinks(entryIO)
+	<-started
+	_ = brain.TrigLinks(entryRouter)
+
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expect neuron in default pool not blocked by io pool")
+	}
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+func TestReducerParallelAppend(t *testing.T) {
+	const workers = 10
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("messages", core.AppendReducer)
+	expect := make([]string, 0, workers)
+	for i := 0; i < workers; i++ {
+		msg := fmt.Sprintf("worker-%d", i)
+		expect = append(expect, msg)
+		worker := bp.AddNeuron(func(bc processor.BrainContext) error {
+			return bc.SetMemory("messages", []string{msg})
+		})
+		_, _ = bp.AddEntryLinkTo(worker)
+	}
+
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithNeuronWorkerNum(workers))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	messages := reflect.ValueOf(brain.GetMemory("messages"))
+	if messages.Kind() != reflect.Slice {
+		t.Fatalf("expect messages of list, got %T", brain.GetMemory("messages"))
+	}
+	got := make([]string, 0, messages.Len())
+	for i := 0; i < messages.Len(); i++ {
+		got = append(got, fmt.Sprint(messages.Index(i).Interface()))
+	}
+	sort.Strings(got)
+	sort.Strings(expect)
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect messages %v, got %v", expect, got)
+	}
+}
+
+func TestReducerMergeMapAndMax(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("scores", core.MergeMapReducer)
+	bp.AddReducer("best", func(old, new any) (any, error) {
+		return nil, errors.New("overridden by brain option")
+	})
+	brain := brainlocal.BuildBrain(bp, brainlocal.WithReducer("best", core.MaxReducer))
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	for _, kv := range [][]any{
+		{"scores", map[string]int{"alice": 1, "bob": 2}, "best", 2},
+		{"scores", map[string]int{"bob": 5, "carol": 3}, "best", 5},
+		{"best", 4},
+	} {
+		if err := brain.SetMemory(kv...); err != nil {
+			t.Fatalf("set memory failed: %v", err)
+		}
+	}
+
+	scores := map[string]int{}
+	iter := reflect.ValueOf(brain.GetMemory("scores")).MapRange()
+	for iter.Next() {
+		scores[iter.Key().String()] = asInt(iter.Value().Interface())
+	}
+	if expect := map[string]int{"alice": 1, "bob": 5, "carol": 3}; !reflect.DeepEqual(scores, expect) {
+		t.Fatalf("expect scores %v, got %v", expect, scores)
+	}
+	if got := asInt(brain.GetMemory("best")); got != 5 {
+		t.Fatalf("expect best 5, got %d", got)
+	}
+}
+
+func TestReducerError(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	bp.AddReducer("count", func(old, new any) (any, error) {
+		if asInt(new) < 0 {
+			return nil, errors.New("count can not be negative")
+		}
+		return asInt(old) + asInt(new), nil
+	})
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	// old is nil for the first time, asInt(nil) is -1
+	if err := brain.SetMemory("count", 3); err != nil {
+		t.Fatalf("set memory failed: %v", err)
+	}
+	if err := brain.SetMemory("count", -1); err == nil {
+		t.Fatalf("expect error of reducer")
+	}
+	if got := asInt(brain.GetMemory("count")); got != 2 {
+		t.Fatalf("expect count 2, got %d", got)
+	}
+}
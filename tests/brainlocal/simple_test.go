@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -33,7 +34,7 @@ func TestSimpleBrain(t *testing.T) {
 
 	name := brain.GetMemory("name").(string)
 	fmt.Printf("result: my name is %s.\n", name)
-	brain.Shutdown()
+	_ = brain.Shutdown(context.Background())
 }
 
 func fn1(b processor.BrainContext) error {
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+// joinBlueprint links workers to a join neuron in one trigger group declared with opts,
+// the last worker blocks until release is closed.
+func joinBlueprint(workers int, release chan struct{}, joined *int32, opts ...core.TriggerGroupOption) (core.Blueprint, core.Neuron) {
+	bp := zenmodel.NewBlueprint()
+	join := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(joined, 1)
+		return nil
+	})
+	inLinks := make([]core.Link, 0, workers)
+	for i := 0; i < workers; i++ {
+		blocked := i == workers-1
+		worker := bp.AddNeuron(func(bc processor.BrainContext) error {
+			if blocked {
+				<-release
+			} else {
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		})
+		_, _ = bp.AddEntryLinkTo(worker)
+		l, _ := bp.AddLink(worker, join)
+		inLinks = append(inLinks, l)
+	}
+	_ = join.AddTriggerGroupWithOptions(inLinks, opts...)
+
+	return bp, join
+}
+
+func waitJoined(t *testing.T, brain core.Brain, join core.Neuron) {
+	deadline := time.Now().Add(10 * time.Second)
+	for brain.Status().Neurons[join.GetID()].Succeed == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("join neuron is not processed before the blocked worker done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTriggerGroupOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []core.TriggerGroupOption
+	}{
+		{name: "quorum", opts: []core.TriggerGroupOption{core.WithQuorum(2)}},
+		{name: "timeout", opts: []core.TriggerGroupOption{core.WithTriggerTimeout(50 * time.Millisecond)}},
+		{name: "first wins", opts: []core.TriggerGroupOption{core.WithFirstWins()}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var joined int32
+			release := make(chan struct{})
+			bp, join := joinBlueprint(3, release, &joined, c.opts...)
+
+			brain := brainlocal.BuildBrain(bp)
+			defer func() { _ = brain.Shutdown(context.Background()) }()
+			_ = brain.Entry()
+			waitJoined(t, brain, join)
+			// cast of the blocked worker is late, it should not fire the join neuron again
+			close(release)
+			waitWithTimeout(t, brain, 10*time.Second)
+
+			if got := atomic.LoadInt32(&joined); got != 1 {
+				t.Fatalf("expect join neuron processed once, got %d", got)
+			}
+		})
+	}
+}
+
+func TestTriggerGroupAllLinks(t *testing.T) {
+	var joined int32
+	release := make(chan struct{})
+	bp, join := joinBlueprint(3, release, &joined)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	time.Sleep(100 * time.Millisecond)
+	if n := brain.Status().Neurons[join.GetID()]; n.Process != 0 {
+		t.Fatalf("expect join neuron waits all links, got %+v", n)
+	}
+	close(release)
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := atomic.LoadInt32(&joined); got != 1 {
+		t.Fatalf("expect join neuron processed once, got %d", got)
+	}
+}
+
+// TestTriggerGroupLateLinkNextIteration checks that link of worker which is not activated when quorum group fires
+// is not late, its cast in the next iteration is not ignored
+func TestTriggerGroupLateLinkNextIteration(t *testing.T) {
+	var joined int32
+	bp := zenmodel.NewBlueprint()
+	router := bp.AddNeuron(func(bc processor.BrainContext) error {
+		iteration, _ := bc.GetMemory("iteration").(int)
+		return bc.SetMemory("iteration", iteration+1)
+	}, core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+		switch asInt(bcr.GetMemory("iteration")) {
+		case 1:
+			return "first"
+		case 2:
+			return "second"
+		default:
+			return "done"
+		}
+	}))
+	join := bp.AddNeuron(func(bc processor.BrainContext) error {
+		atomic.AddInt32(&joined, 1)
+		return nil
+	})
+	inLinks := make([]core.Link, 0, 3)
+	outLinks := make([]core.Link, 0, 3)
+	for i := 0; i < 3; i++ {
+		worker := bp.AddNeuron(nopProcess)
+		out, _ := bp.AddLink(router, worker)
+		in, _ := bp.AddLink(worker, join)
+		outLinks = append(outLinks, out)
+		inLinks = append(inLinks, in)
+	}
+	_ = join.AddTriggerGroupWithOptions(inLinks, core.WithQuorum(2))
+	_, _ = bp.AddEntryLinkTo(router)
+	_, _ = bp.AddLink(join, router)
+	end, _ := bp.AddEndLinkFrom(router)
+	_ = router.AddCastGroup("first", outLinks[0], outLinks[1])
+	_ = router.AddCastGroup("second", outLinks[0], outLinks[2])
+	_ = router.AddCastGroup("done", end)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if got := atomic.LoadInt32(&joined); got != 2 {
+		t.Fatalf("expect join neuron processed in both iterations, got %d", got)
+	}
+}
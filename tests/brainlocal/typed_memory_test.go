@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zenmodel/zenmodel"
+	"github.com/zenmodel/zenmodel/brainlocal"
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/memory"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+type typedProfile struct {
+	Name string   `json:"name"`
+	ID   int64    `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+var (
+	profileKey = memory.NewKey[typedProfile]("profile")
+	counterKey = memory.NewKey[int]("counter")
+	answerKey  = memory.NewKey[string]("answer")
+)
+
+func TestTypedMemory(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	_ = bp.AddNeuron(nopProcess)
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	profile := typedProfile{Name: "zen", ID: 1<<60 + 1, Tags: []string{"a", "b"}}
+	if err := memory.Set(brain, profileKey, profile); err != nil {
+		t.Fatalf("set memory error: %v", err)
+	}
+	got, ok, err := memory.Get(brain, profileKey)
+	if err != nil || !ok || !reflect.DeepEqual(got, profile) {
+		t.Fatalf("expect %+v, got %+v, %v, %v", profile, got, ok, err)
+	}
+
+	if _, ok, err = memory.Get(brain, memory.NewKey[typedProfile]("missing")); ok || err != nil {
+		t.Fatalf("expect missing memory not found without error, got %v, %v", ok, err)
+	}
+
+	_ = memory.Set(brain, answerKey, "forty-two")
+	if _, ok, err = memory.Get(brain, memory.NewKey[int](answerKey.Name())); !ok || err == nil {
+		t.Fatalf("expect type mismatch error, got %v, %v", ok, err)
+	}
+
+	_ = brain.SetMemory("score", 90)
+	if score, ok, err := memory.Get(brain, memory.NewKey[float64]("score")); err != nil || !ok || score != 90 {
+		t.Fatalf("expect score 90 as float64, got %v, %v, %v", score, ok, err)
+	}
+}
+
+func TestTypedMemoryInProcessor(t *testing.T) {
+	bp := zenmodel.NewBlueprint()
+	count := bp.AddNeuron(func(bc processor.BrainContext) error {
+		return memory.Update(bc, counterKey, func(old int, ok bool) (int, error) {
+			if !ok {
+				return 1, nil
+			}
+			return old + 1, nil
+		})
+	}, core.WithSelectFn(func(bcr processor.BrainContextReader) string {
+		if n, _, _ := memory.Get(bcr, counterKey); n < 3 {
+			return "again"
+		}
+		return "done"
+	}))
+	again, _ := bp.AddLink(count, count)
+	done, _ := bp.AddEndLinkFrom(count)
+	_, _ = bp.AddEntryLinkTo(count)
+	_ = count.AddCastGroup("again", again)
+	_ = count.AddCastGroup("done", done)
+
+	brain := brainlocal.BuildBrain(bp)
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+	_ = brain.Entry()
+	waitWithTimeout(t, brain, 10*time.Second)
+
+	if n, ok, err := memory.Get(brain, counterKey); err != nil || !ok || n != 3 {
+		t.Fatalf("expect counter 3, got %v, %v, %v", n, ok, err)
+	}
+}
+
+// TestTypedMemoryUpdateParallel checks that Update of brain runs in memory transaction
+func TestTypedMemoryUpdateParallel(t *testing.T) {
+	const workers = 20
+	brain := brainlocal.BuildBrain(zenmodel.NewBlueprint())
+	defer func() { _ = brain.Shutdown(context.Background()) }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := memory.Update(brain, counterKey, func(old int, ok bool) (int, error) {
+				// widen the window between read and write
+				time.Sleep(time.Millisecond)
+				return old + 1, nil
+			})
+			if err != nil {
+				t.Errorf("update memory failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, ok, err := memory.Get(brain, counterKey); err != nil || !ok || n != workers {
+		t.Fatalf("expect counter %d, got %v, %v, %v", workers, n, ok, err)
+	}
+}
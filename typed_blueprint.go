@@ -0,0 +1,58 @@
+package zenmodel
+
+import (
+	"github.com/zenmodel/zenmodel/core"
+	"github.com/zenmodel/zenmodel/memory"
+	"github.com/zenmodel/zenmodel/processor"
+)
+
+// TypedBlueprint is a Blueprint whose memory model is the state struct S, each field of S is one memory,
+// see memory.Schema. Processors and selectors receive the typed state instead of untyped memory,
+// it runs on any brain because the state is stored in brain memory.
+type TypedBlueprint[S any] struct {
+	core.Blueprint
+	schema *memory.Schema[S]
+}
+
+// NewTypedBlueprint creates TypedBlueprint of state struct S, it panics if S is not a struct
+func NewTypedBlueprint[S any]() *TypedBlueprint[S] {
+	schema, err := memory.NewSchema[S]()
+	if err != nil {
+		panic(err)
+	}
+
+	return &TypedBlueprint[S]{
+		Blueprint: NewBlueprint(),
+		schema:    schema,
+	}
+}
+
+// Schema returns the memory model of state
+func (b *TypedBlueprint[S]) Schema() *memory.Schema[S] {
+	return b.schema
+}
+
+// AddStateNeuron adds Neuron whose process function receives the typed state of current brain
+func (b *TypedBlueprint[S]) AddStateNeuron(processFn func(bc processor.BrainContext, state *memory.State[S]) error,
+	withOpts ...core.NeuronOption) core.Neuron {
+	return b.AddNeuron(func(bc processor.BrainContext) error {
+		return processFn(bc, b.schema.Bind(bc))
+	}, withOpts...)
+}
+
+// StateSelectFn converts select function of state to the select function of Neuron,
+// it selects no cast group if state can not be read.
+func (b *TypedBlueprint[S]) StateSelectFn(selectFn func(state S) string) func(bcr processor.BrainContextReader) string {
+	return func(bcr processor.BrainContextReader) string {
+		state, err := b.schema.Read(bcr)
+		if err != nil {
+			return ""
+		}
+		return selectFn(state)
+	}
+}
+
+// State returns the typed state of brain, e.g. to set initial state before Entry or read the result after run
+func (b *TypedBlueprint[S]) State(brain core.Brain) *memory.State[S] {
+	return b.schema.Bind(brain)
+}